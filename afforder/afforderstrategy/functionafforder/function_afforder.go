@@ -17,12 +17,22 @@ import (
 type FunctionAfforder struct {
 	permissibleFunctions   []*llm.FunctionDef
 	permissibleFunctionMap map[string]*llm.FunctionDef
+	language               language.Language
+}
+
+type Options struct {
+	// The language that the browser is rendered in; defaults to markdown.
+	Language language.Language
 }
 
 //go:embed system_prompt_to_act_on_browser.txt
 var systemPromptToActOnBrowser string
 
-func New() afforderstrategy.AfforderStrategy {
+func New(options *Options) afforderstrategy.AfforderStrategy {
+	lang := language.LanguageMD
+	if options != nil && options.Language != "" {
+		lang = options.Language
+	}
 	permissibleFunctions := []*llm.FunctionDef{
 		{
 			Name: "click",
@@ -50,10 +60,81 @@ func New() afforderstrategy.AfforderStrategy {
 						Type:        "string",
 						Description: "The text to send to the element",
 					},
+					"append": {
+						Type:        "boolean",
+						Description: "Whether to append the text to the existing content of the element instead of replacing it; defaults to false",
+					},
 				},
 				Required: []string{"id", "text"},
 			},
 		},
+		{
+			Name: "press_key",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"key": {
+						Type:        "string",
+						Description: "The key to press, such as \"Enter\", \"Tab\", \"Escape\", or \"ArrowDown\"; modifiers can be combined with \"+\", such as \"Shift+Tab\"",
+					},
+					"id": {
+						Type:        "string",
+						Description: "The id of the element to focus before pressing the key; if omitted, the key is sent to the focused element",
+					},
+				},
+				Required: []string{"key"},
+			},
+		},
+		{
+			Name: "select_option",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"id": {
+						Type:        "string",
+						Description: "The id of the select element",
+					},
+					"option": {
+						Type:        "string",
+						Description: "The value or the displayed label of the option to select",
+					},
+				},
+				Required: []string{"id", "option"},
+			},
+		},
+		{
+			Name: "toggle",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"id": {
+						Type:        "string",
+						Description: "The id of the checkbox or radio button to toggle",
+					},
+				},
+				Required: []string{"id"},
+			},
+		},
+		{
+			Name: "upload_file",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"id": {
+						Type:        "string",
+						Description: "The id of the file input to attach the files to",
+					},
+					"files": {
+						Type:        "array",
+						Description: "The paths of the local files to attach, relative to the upload directories; attaching files replaces any files that were attached before",
+						Items: &llm.ArrayItems{
+							Type: "string",
+						},
+					},
+				},
+				Required: []string{"id", "files"},
+			},
+		},
 		{
 			Name: "navigate",
 			Parameters: llm.Parameters{
@@ -61,12 +142,230 @@ func New() afforderstrategy.AfforderStrategy {
 				Properties: map[string]llm.Property{
 					"url": {
 						Type:        "string",
-						Description: "The url to navigate the browser to",
+						Description: "The url to navigate the browser to, such as https://example.com, http://localhost:8080, or a file: or data: url",
 					},
 				},
 				Required: []string{"url"},
 			},
 		},
+		{
+			Name:        "go_back",
+			Description: "Go back to the previous page in the browser history",
+			Parameters: llm.Parameters{
+				Type:       "object",
+				Properties: map[string]llm.Property{},
+				Required:   []string{},
+			},
+		},
+		{
+			Name:        "go_forward",
+			Description: "Go forward to the next page in the browser history",
+			Parameters: llm.Parameters{
+				Type:       "object",
+				Properties: map[string]llm.Property{},
+				Required:   []string{},
+			},
+		},
+		{
+			Name:        "reload",
+			Description: "Reload the current page",
+			Parameters: llm.Parameters{
+				Type:       "object",
+				Properties: map[string]llm.Property{},
+				Required:   []string{},
+			},
+		},
+		{
+			Name:        "wait_for",
+			Description: "Wait until some text or an element appears on the page, such as search results that are still loading",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"text": {
+						Type:        "string",
+						Description: "The text to wait for; matching is case-insensitive",
+					},
+					"selector": {
+						Type:        "string",
+						Description: "A CSS selector of an element to wait for",
+					},
+					"timeout_seconds": {
+						Type:        "number",
+						Description: "The maximum number of seconds to wait; defaults to 10",
+					},
+				},
+				Required: []string{},
+			},
+		},
+		{
+			Name:        "accept_dialog",
+			Description: "Accept the alert, confirm, prompt, or leave-page dialog that is open",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"prompt_text": {
+						Type:        "string",
+						Description: "The text to enter into a prompt dialog before accepting it; defaults to the default prompt text",
+					},
+				},
+				Required: []string{},
+			},
+		},
+		{
+			Name:        "dismiss_dialog",
+			Description: "Dismiss the alert, confirm, prompt, or leave-page dialog that is open",
+			Parameters: llm.Parameters{
+				Type:       "object",
+				Properties: map[string]llm.Property{},
+				Required:   []string{},
+			},
+		},
+		{
+			Name:        "open_tab",
+			Description: "Open a new tab and make it the active tab",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"url": {
+						Type:        "string",
+						Description: "The url to open in the new tab; if omitted, the tab is blank",
+					},
+				},
+				Required: []string{},
+			},
+		},
+		{
+			Name: "switch_tab",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"tab_id": {
+						Type:        "string",
+						Description: "The id of the tab to switch to, as listed in the tabs header",
+					},
+				},
+				Required: []string{"tab_id"},
+			},
+		},
+		{
+			Name: "close_tab",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"tab_id": {
+						Type:        "string",
+						Description: "The id of the tab to close; if omitted, the active tab is closed",
+					},
+				},
+				Required: []string{},
+			},
+		},
+		{
+			Name:        "inspect_network",
+			Description: "Read the JSON data that the page loaded from its own API, which is often more complete and structured than the page itself",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"url_pattern": {
+						Type:        "string",
+						Description: "Only return responses whose url contains this text, such as \"/api/search\"; if omitted, the most recent responses are returned",
+					},
+					"max_tokens": {
+						Type:        "integer",
+						Description: fmt.Sprintf("The maximum number of tokens of the returned responses, which are trimmed to fit; defaults to %d and can be at most %d", browser.DefaultInspectNetworkMaxTokens, browser.MaxInspectNetworkMaxTokens),
+					},
+				},
+				Required: []string{},
+			},
+		},
+		{
+			Name:        "extract",
+			Description: "Extract rows of data as JSON from a table, a list, or repeated items such as search results or product cards on the current page",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"fields": {
+						Type:        "array",
+						Description: "The names of the fields of each row, such as [\"name\", \"price\"]; they are matched to the columns of a table or the parts of each item",
+						Items: &llm.ArrayItems{
+							Type: "string",
+						},
+					},
+					"schema": {
+						Type:        "string",
+						Description: "A description of the rows to extract if fields are not supplied, such as \"the title, price, and rating of each product\"; if both are omitted, all columns of the largest table or list are extracted",
+					},
+				},
+				Required: []string{},
+			},
+		},
+		{
+			Name:        "hover",
+			Description: "Move the mouse over an element, which can open menus or show tooltips",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"id": {
+						Type:        "string",
+						Description: "The id of the element to hover over",
+					},
+				},
+				Required: []string{"id"},
+			},
+		},
+		{
+			Name:        "drag",
+			Description: "Drag an element with the mouse and drop it onto another element",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"from_id": {
+						Type:        "string",
+						Description: "The id of the element to drag",
+					},
+					"to_id": {
+						Type:        "string",
+						Description: "The id of the element to drop it onto",
+					},
+				},
+				Required: []string{"from_id", "to_id"},
+			},
+		},
+		{
+			Name:        "click_at",
+			Description: "Click a point of the page with the mouse; only use this if the element to click has no id",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"x": {
+						Type:        "number",
+						Description: "The distance of the point from the left edge of the viewport in css pixels",
+					},
+					"y": {
+						Type:        "number",
+						Description: "The distance of the point from the top edge of the viewport in css pixels",
+					},
+				},
+				Required: []string{"x", "y"},
+			},
+		},
+		{
+			Name: "scroll",
+			Parameters: llm.Parameters{
+				Type: "object",
+				Properties: map[string]llm.Property{
+					"direction": {
+						Type:        "string",
+						Description: "The direction to scroll the page by one screen, either \"up\" or \"down\"",
+					},
+					"id": {
+						Type:        "string",
+						Description: "The id of an element to scroll into view; if supplied, direction is ignored",
+					},
+				},
+				Required: []string{},
+			},
+		},
 		{
 			Name: "message",
 			Parameters: llm.Parameters{
@@ -101,11 +400,16 @@ func New() afforderstrategy.AfforderStrategy {
 	return &FunctionAfforder{
 		permissibleFunctions:   permissibleFunctions,
 		permissibleFunctionMap: m,
+		language:               lang,
 	}
 }
 
 func (a *FunctionAfforder) GetAffordances(ctx context.Context, traj *trajectory.Trajectory, br *browser.Browser) ([]*llm.Message, []*llm.FunctionDef, error) {
-	pageRender, err := br.Render(language.LanguageMD)
+	lang := a.language
+	if lang == "" {
+		lang = language.LanguageMD
+	}
+	pageRender, err := br.Render(lang)
 	if err != nil {
 		return nil, nil, fmt.Errorf("browser failed to render page: %w", err)
 	}
@@ -165,9 +469,92 @@ func (a *FunctionAfforder) ParseNextAction(name string, arguments string) (traje
 	case "click":
 		return trajectory.NewBrowserClickAction(virtualid.VirtualID(args["id"].(string))), nil
 	case "send_keys":
-		return trajectory.NewBrowserSendKeysAction(virtualid.VirtualID(args["id"].(string)), args["text"].(string)), nil
+		shouldAppend, _ := args["append"].(bool)
+		return trajectory.NewBrowserSendKeysAction(virtualid.VirtualID(args["id"].(string)), args["text"].(string), shouldAppend), nil
+	case "press_key":
+		id, _ := args["id"].(string)
+		return trajectory.NewBrowserPressKeyAction(virtualid.VirtualID(id), args["key"].(string)), nil
+	case "select_option":
+		return trajectory.NewBrowserSelectOptionAction(virtualid.VirtualID(args["id"].(string)), args["option"].(string)), nil
+	case "toggle":
+		return trajectory.NewBrowserToggleAction(virtualid.VirtualID(args["id"].(string))), nil
+	case "upload_file":
+		rawFiles, ok := args["files"].([]any)
+		if !ok {
+			return nil, fmt.Errorf("files must be an array of strings")
+		}
+		files := make([]string, len(rawFiles))
+		for i, rawFile := range rawFiles {
+			if files[i], ok = rawFile.(string); !ok {
+				return nil, fmt.Errorf("files must be an array of strings")
+			}
+		}
+		return trajectory.NewBrowserUploadFileAction(virtualid.VirtualID(args["id"].(string)), files), nil
 	case "navigate":
 		return trajectory.NewBrowserNavigateAction(args["url"].(string)), nil
+	case "go_back":
+		return trajectory.NewBrowserGoBackAction(), nil
+	case "go_forward":
+		return trajectory.NewBrowserGoForwardAction(), nil
+	case "reload":
+		return trajectory.NewBrowserReloadAction(), nil
+	case "wait_for":
+		text, _ := args["text"].(string)
+		selector, _ := args["selector"].(string)
+		timeoutSeconds, _ := args["timeout_seconds"].(float64)
+		if text == "" && selector == "" {
+			return nil, fmt.Errorf("either text or selector must be supplied to wait_for")
+		}
+		return trajectory.NewBrowserWaitForAction(text, selector, timeoutSeconds), nil
+	case "accept_dialog":
+		promptText, _ := args["prompt_text"].(string)
+		return trajectory.NewBrowserAcceptDialogAction(promptText), nil
+	case "dismiss_dialog":
+		return trajectory.NewBrowserDismissDialogAction(), nil
+	case "open_tab":
+		url, _ := args["url"].(string)
+		return trajectory.NewBrowserOpenTabAction(url), nil
+	case "switch_tab":
+		return trajectory.NewBrowserSwitchTabAction(args["tab_id"].(string)), nil
+	case "close_tab":
+		tabID, _ := args["tab_id"].(string)
+		return trajectory.NewBrowserCloseTabAction(tabID), nil
+	case "extract":
+		fields := []string{}
+		if rawFields, ok := args["fields"].([]any); ok {
+			for _, rawField := range rawFields {
+				field, ok := rawField.(string)
+				if !ok {
+					return nil, fmt.Errorf("fields must be an array of strings")
+				}
+				fields = append(fields, field)
+			}
+		}
+		schema, _ := args["schema"].(string)
+		return trajectory.NewBrowserExtractAction(fields, schema), nil
+	case "inspect_network":
+		urlPattern, _ := args["url_pattern"].(string)
+		maxTokens, _ := args["max_tokens"].(float64)
+		return trajectory.NewBrowserInspectNetworkAction(urlPattern, int(maxTokens)), nil
+	case "hover":
+		return trajectory.NewBrowserHoverAction(virtualid.VirtualID(args["id"].(string))), nil
+	case "drag":
+		return trajectory.NewBrowserDragAction(virtualid.VirtualID(args["from_id"].(string)), virtualid.VirtualID(args["to_id"].(string))), nil
+	case "click_at":
+		x, xOK := args["x"].(float64)
+		y, yOK := args["y"].(float64)
+		if !xOK || !yOK {
+			return nil, fmt.Errorf("x and y must be numbers")
+		}
+		return trajectory.NewBrowserClickAtAction(x, y), nil
+	case "scroll":
+		if id, ok := args["id"].(string); ok && id != "" {
+			return trajectory.NewBrowserScrollToElementAction(virtualid.VirtualID(id)), nil
+		} else if direction, ok := args["direction"].(string); ok && direction != "" {
+			return trajectory.NewBrowserScrollAction(direction), nil
+		} else {
+			return nil, fmt.Errorf("either direction or id must be supplied to scroll")
+		}
 	case "message":
 		return trajectory.NewMessage(trajectory.MessageAuthorAgent, args["text"].(string)), nil
 	case "task_not_possible":
@@ -4,6 +4,7 @@ import (
 	"collaborativebrowser/afforder/afforderstrategy"
 	"collaborativebrowser/afforder/afforderstrategy/filterafforder"
 	"collaborativebrowser/afforder/afforderstrategy/functionafforder"
+	"collaborativebrowser/browser/language"
 	"collaborativebrowser/llm"
 	"log"
 )
@@ -13,6 +14,8 @@ type AfforderStrategyID string
 const (
 	AfforderStrategyIDFunctionAfforder AfforderStrategyID = "function"
 	AfforderStrategyIDFilterAfforder   AfforderStrategyID = "filter"
+	// The function afforder with the page rendered as its accessibility tree instead of markdown.
+	AfforderStrategyIDAXFunctionAfforder AfforderStrategyID = "function_ax"
 )
 
 const DefaultAfforderStrategyID = AfforderStrategyIDFunctionAfforder
@@ -20,11 +23,13 @@ const DefaultAfforderStrategyID = AfforderStrategyIDFunctionAfforder
 func AfforderStrategyByID(id AfforderStrategyID, models *llm.Models) afforderstrategy.AfforderStrategy {
 	switch id {
 	case AfforderStrategyIDFunctionAfforder:
-		return functionafforder.New()
+		return functionafforder.New(nil)
 	case AfforderStrategyIDFilterAfforder:
 		return filterafforder.New(models)
+	case AfforderStrategyIDAXFunctionAfforder:
+		return functionafforder.New(&functionafforder.Options{Language: language.LanguageAXTree})
 	default:
 		log.Printf("invalid afforder strategy ID: %s; defaulting to %s", id, DefaultAfforderStrategyID)
-		return functionafforder.New()
+		return functionafforder.New(nil)
 	}
 }
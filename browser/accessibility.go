@@ -0,0 +1,107 @@
+package browser
+
+import (
+	"collaborativebrowser/browser/virtualid"
+	"collaborativebrowser/translators/ax2md"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Returns the accessibility tree of the active tab, including same-origin iframes, as a JSON encoded
+// ax2md.Document.
+func (b *Browser) getAXTree() (string, error) {
+	doc := &ax2md.Document{
+		VirtualIDs: make(map[cdp.BackendNodeID]string),
+	}
+	if err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		node, err := dom.GetDocument().WithDepth(-1).WithPierce(true).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting document: %w", err)
+		}
+		collectVirtualIDs(node, doc.VirtualIDs)
+		nodes, err := accessibility.GetFullAXTree().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting accessibility tree: %w", err)
+		}
+		doc.Nodes = nodes
+		frameTree, err := page.GetFrameTree().Do(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting frame tree: %w", err)
+		}
+		doc.Nodes = appendFrameAXTrees(ctx, frameTree.ChildFrames, doc.Nodes)
+		return nil
+	})); err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("error encoding accessibility tree: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func collectVirtualIDs(n *cdp.Node, virtualIDs map[cdp.BackendNodeID]string) {
+	if id, ok := n.Attribute(virtualid.VirtualIDDataAttr); ok {
+		virtualIDs[n.BackendNodeID] = id
+	}
+	for _, child := range n.Children {
+		collectVirtualIDs(child, virtualIDs)
+	}
+	for _, shadowRoot := range n.ShadowRoots {
+		collectVirtualIDs(shadowRoot, virtualIDs)
+	}
+	if n.ContentDocument != nil {
+		collectVirtualIDs(n.ContentDocument, virtualIDs)
+	}
+}
+
+// Fetches the accessibility trees of child frames and attaches each of them to the node of the element that
+// owns the frame. Frames that cannot be fetched, such as out-of-process cross-origin frames, are skipped.
+func appendFrameAXTrees(ctx context.Context, frames []*page.FrameTree, nodes []*accessibility.Node) []*accessibility.Node {
+	for _, frame := range frames {
+		frameNodes, err := accessibility.GetFullAXTree().WithFrameID(frame.Frame.ID).Do(ctx)
+		if err != nil {
+			log.Printf("error getting accessibility tree of frame %s: %v", frame.Frame.ID, err)
+			continue
+		}
+		owner, _, err := dom.GetFrameOwner(frame.Frame.ID).Do(ctx)
+		if err != nil {
+			log.Printf("error getting owner of frame %s: %v", frame.Frame.ID, err)
+			continue
+		}
+		// node ids are only unique within a frame
+		prefix := accessibility.NodeID(string(frame.Frame.ID) + ":")
+		for _, n := range frameNodes {
+			n.NodeID = prefix + n.NodeID
+			if n.ParentID != "" {
+				n.ParentID = prefix + n.ParentID
+			}
+			for i := range n.ChildIDs {
+				n.ChildIDs[i] = prefix + n.ChildIDs[i]
+			}
+		}
+		for _, ownerNode := range nodes {
+			if ownerNode.BackendDOMNodeID != owner {
+				continue
+			}
+			for _, n := range frameNodes {
+				if n.ParentID == "" {
+					n.ParentID = ownerNode.NodeID
+					ownerNode.ChildIDs = append(ownerNode.ChildIDs, n.NodeID)
+				}
+			}
+			break
+		}
+		nodes = append(nodes, frameNodes...)
+		nodes = appendFrameAXTrees(ctx, frame.ChildFrames, nodes)
+	}
+	return nodes
+}
@@ -5,43 +5,85 @@ import (
 	"collaborativebrowser/browser/virtualid"
 	"collaborativebrowser/trajectory"
 	"collaborativebrowser/translators"
+	"collaborativebrowser/translators/ax2md"
 	"collaborativebrowser/translators/html2md"
-	"collaborativebrowser/utils/slicesx"
 	"context"
 	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/chromedp/cdproto/dom"
 	"github.com/chromedp/chromedp"
 )
 
 type Browser struct {
-	mu                *sync.Mutex
-	ctx               context.Context
-	cancel            context.CancelFunc
-	options           []BrowserOption
-	vIDGenerator      virtualid.VirtualIDGenerator
-	translators       map[language.Language]translators.Translator
-	display           *BrowserDisplay
+	mu *sync.Mutex
+	// the context of the active tab
+	ctx context.Context
+	// the context and cancel func of the first tab, which owns the browser process
+	browserCtx       context.Context
+	cancel           context.CancelFunc
+	tabs             []*tab
+	activeTab        *tab
+	tabCounter       int
+	launchConfig     *LaunchConfig
+	vIDGenerator     virtualid.VirtualIDGenerator
+	translators      map[language.Language]translators.Translator
+	display          *BrowserDisplay
+	renderMode       RenderMode
+	waitOptions      *WaitOptions
+	uploadDirs       []string
+	downloads        *downloadTracker
+	interceptor      *requestInterceptor
+	dialogPolicy     DialogPolicy
+	navigationPolicy *NavigationPolicy
+	urlCanonicalizer URLCanonicalizer
+	emulationProfile *EmulationProfile
+	console          *consoleTracker
+	networkLog       *networkRecorder
+	// the navigations that were blocked outside of an action, such as tabs that were opened at blocked urls
+	blockedNavigations []*NavigationBlockedError
+	// the last translation of the page, which is kept while a dialog blocks the page
+	pageContent       string
+	screenshotMode    ScreenshotMode
+	lastScreenshot    *Screenshot
+	lastExtraction    *Extraction
 	isRunningHeadless bool
+	// whether the browser was not launched by this process, such as a browser that the user has open
+	isRemote bool
 }
 
-type BrowserOption string
-
-const (
-	BrowserOptionHeadful                           BrowserOption = "headful"
-	BrowserOptionAttemptToDisableAutomationMessage BrowserOption = "attempt-to-disable-automation-message"
-)
-
 type BrowserDisplay struct {
 	HTML     string
 	MD       string
 	Location string
+	Tabs     []*TabDisplay
+	// the dialog that is open in the active tab, if any
+	Dialog *Dialog
+	// the emulation profile of the browser, if any
+	Emulation *EmulationProfile
 }
 
+type RenderMode string
+
+const (
+	// Renders the entire document.
+	RenderModeFull RenderMode = "full"
+	// Renders the entire document with markers for the content that is above, inside, and below the viewport.
+	RenderModeViewport RenderMode = "viewport"
+)
+
+type ScrollDirection string
+
+const (
+	ScrollDirectionUp   ScrollDirection = "up"
+	ScrollDirectionDown ScrollDirection = "down"
+)
+
 type ElementType string
 
 const (
@@ -49,27 +91,94 @@ const (
 	ElementTypeInput    ElementType = "input"
 	ElementTypeLink     ElementType = "a"
 	ElementTypeTextArea ElementType = "textarea"
+	ElementTypeSelect   ElementType = "select"
+	ElementTypeCheckbox ElementType = "checkbox"
+	ElementTypeRadio    ElementType = "radio"
 	ElementTypeOther    ElementType = "other"
 )
 
 func (b *Browser) updateDisplay() error {
+	if err := b.syncTabs(); err != nil {
+		log.Println("error syncing tabs:", err)
+	}
+	if dialog := b.GetOpenDialog(); dialog != nil {
+		b.display.Tabs = b.getTabDisplays()
+		b.display.Dialog = dialog
+		b.display.Emulation = b.emulationProfile
+		b.display.MD = renderDisplay(b.display.Tabs, b.emulationProfile, dialog, b.pageContent)
+		return nil
+	}
 	if location, err := b.getLocation(); err != nil {
 		return fmt.Errorf("error getting location: %w", err)
-	} else if html, err := b.getHTML(); err != nil {
+	} else if html, err := b.getRenderableHTML(); err != nil {
 		return fmt.Errorf("error getting html for location %s: %w", location, err)
 	} else if md, err := b.translators[language.LanguageMD].Translate(html); err != nil {
 		return fmt.Errorf("error translating html to %s for location %s: %w", language.LanguageMD, location, err)
 	} else {
+		b.pageContent = md
 		b.display.HTML = html
 		b.display.Location = location
-		b.display.MD = md
+		b.display.Tabs = b.getTabDisplays()
+		b.display.Dialog = nil
+		b.display.Emulation = b.emulationProfile
+		b.display.MD = renderDisplay(b.display.Tabs, b.emulationProfile, nil, md)
 		return nil
 	}
 }
 
 func (b *Browser) AcceptAction(action *trajectory.BrowserAction) (string, error) {
+	previousDialog := b.GetOpenDialog()
+	if previousDialog != nil && !isDialogActionType(action.Type) && !isTabActionType(action.Type) {
+		return "", fmt.Errorf("cannot %s while a %s is open; accept or dismiss it first", action.Type, previousDialog)
+	}
+	b.console.beginAction()
+	b.networkLog.beginAction()
+	response, err := b.acceptAction(action)
+	var blocked *NavigationBlockedError
+	if errors.As(err, &blocked) {
+		// the navigation was not permitted, which is reported so that another way can be tried
+		if err := b.updateDisplay(); err != nil {
+			return "", fmt.Errorf("error updating display: %w", err)
+		}
+		response, err = blocked.Error(), nil
+	} else if errors.Is(err, ErrDialogOpen) {
+		// the action opened a dialog before it finished, which blocks the page until it is accepted or dismissed
+		if err := b.updateDisplay(); err != nil {
+			return "", fmt.Errorf("error updating display: %w", err)
+		}
+		response, err = string(action.Type), nil
+	}
+	consoleSummary := b.console.summarizeAction()
+	if err != nil {
+		if consoleSummary != "" {
+			return "", fmt.Errorf("%w; %s", err, consoleSummary)
+		}
+		return "", err
+	}
+	if dialog := b.GetOpenDialog(); dialog != nil && dialog != previousDialog {
+		response = fmt.Sprintf("%s; a %s opened", response, dialog)
+	}
+	for _, handled := range b.takeHandledDialogs() {
+		response = fmt.Sprintf("%s; a %s", response, handled)
+	}
+	for _, blocked := range b.takeBlockedNavigations() {
+		response = fmt.Sprintf("%s; %s", response, blocked)
+	}
+	if consoleSummary != "" {
+		response = fmt.Sprintf("%s; %s", response, consoleSummary)
+	}
+	if apiSummary := b.networkLog.summarizeAction(); apiSummary != "" && action.Type != trajectory.BrowserActionTypeInspectNetwork {
+		response = fmt.Sprintf("%s; %s", response, apiSummary)
+	}
+	return response, nil
+}
+
+func (b *Browser) acceptAction(action *trajectory.BrowserAction) (string, error) {
 	var err error
 	var response string
+	previousTabID := b.ActiveTabID()
+	b.lastScreenshot = nil
+	b.lastExtraction = nil
 	switch action.Type {
 	case trajectory.BrowserActionTypeClick:
 		if err = b.Click(action.ID); err != nil {
@@ -77,7 +186,7 @@ func (b *Browser) AcceptAction(action *trajectory.BrowserAction) (string, error)
 		}
 		response = fmt.Sprintf("clicked %s", action.ID)
 	case trajectory.BrowserActionTypeSendKeys:
-		if err = b.SendKeys(action.ID, action.Text); err != nil {
+		if err = b.SendKeys(action.ID, action.Text, action.Append); err != nil {
 			return "", fmt.Errorf("error sending keys: %w", err)
 		}
 		keysDisplay := action.Text
@@ -85,24 +194,190 @@ func (b *Browser) AcceptAction(action *trajectory.BrowserAction) (string, error)
 			keysDisplay = keysDisplay[:10] + "..."
 		}
 		response = fmt.Sprintf("sent keys \"%s\" to %s", keysDisplay, action.ID)
+	case trajectory.BrowserActionTypePressKey:
+		if err = b.PressKey(action.ID, action.Key); err != nil {
+			return "", fmt.Errorf("error pressing key: %w", err)
+		}
+		if action.ID != "" {
+			response = fmt.Sprintf("pressed %s on %s", action.Key, action.ID)
+		} else {
+			response = fmt.Sprintf("pressed %s", action.Key)
+		}
 	case trajectory.BrowserActionTypeNavigate:
 		if err = b.Navigate(action.URL); err != nil {
 			return "", fmt.Errorf("error navigating: %w", err)
 		}
 		response = fmt.Sprintf("navigated to %s", action.URL)
+	case trajectory.BrowserActionTypeGoBack:
+		if err = b.GoBack(); err != nil {
+			return "", fmt.Errorf("error going back: %w", err)
+		}
+		response = fmt.Sprintf("went back to %s", b.display.Location)
+	case trajectory.BrowserActionTypeGoForward:
+		if err = b.GoForward(); err != nil {
+			return "", fmt.Errorf("error going forward: %w", err)
+		}
+		response = fmt.Sprintf("went forward to %s", b.display.Location)
+	case trajectory.BrowserActionTypeReload:
+		if err = b.Reload(); err != nil {
+			return "", fmt.Errorf("error reloading: %w", err)
+		}
+		response = fmt.Sprintf("reloaded %s", b.display.Location)
+	case trajectory.BrowserActionTypeSelectOption:
+		selected, err := b.SelectOption(action.ID, action.Option)
+		if err != nil {
+			return "", fmt.Errorf("error selecting option: %w", err)
+		}
+		response = fmt.Sprintf("selected option \"%s\" in %s", selected, action.ID)
+	case trajectory.BrowserActionTypeToggle:
+		checked, err := b.Toggle(action.ID)
+		if err != nil {
+			return "", fmt.Errorf("error toggling: %w", err)
+		}
+		if checked {
+			response = fmt.Sprintf("toggled %s; it is now checked", action.ID)
+		} else {
+			response = fmt.Sprintf("toggled %s; it is now unchecked", action.ID)
+		}
+	case trajectory.BrowserActionTypeUploadFile:
+		if err = b.UploadFiles(action.ID, action.Files); err != nil {
+			return "", fmt.Errorf("error uploading files: %w", err)
+		}
+		names := make([]string, len(action.Files))
+		for i, file := range action.Files {
+			names[i] = filepath.Base(file)
+		}
+		response = fmt.Sprintf("attached %s to %s", strings.Join(names, ", "), action.ID)
+	case trajectory.BrowserActionTypeAcceptDialog:
+		dialog := b.GetOpenDialog()
+		if err = b.AcceptDialog(action.Text); err != nil {
+			return "", fmt.Errorf("error accepting dialog: %w", err)
+		}
+		response = fmt.Sprintf("accepted %s", dialog)
+	case trajectory.BrowserActionTypeDismissDialog:
+		dialog := b.GetOpenDialog()
+		if err = b.DismissDialog(); err != nil {
+			return "", fmt.Errorf("error dismissing dialog: %w", err)
+		}
+		response = fmt.Sprintf("dismissed %s", dialog)
+	case trajectory.BrowserActionTypeScroll:
+		if action.ID != "" {
+			if err = b.ScrollTo(action.ID); err != nil {
+				return "", fmt.Errorf("error scrolling to element: %w", err)
+			}
+			response = fmt.Sprintf("scrolled to %s", action.ID)
+		} else {
+			if err = b.Scroll(ScrollDirection(action.Direction)); err != nil {
+				return "", fmt.Errorf("error scrolling: %w", err)
+			}
+			response = fmt.Sprintf("scrolled %s", action.Direction)
+		}
+		if position, err := b.GetScrollPosition(); err != nil {
+			log.Println("error getting scroll position:", err)
+		} else {
+			response = fmt.Sprintf("%s; %s", response, position)
+		}
+	case trajectory.BrowserActionTypeWaitFor:
+		condition := describeWaitCondition(action.Text, action.Selector)
+		found, err := b.WaitFor(action.Text, action.Selector, time.Duration(action.TimeoutSeconds*float64(time.Second)))
+		if err != nil {
+			return "", fmt.Errorf("error waiting for %s: %w", condition, err)
+		} else if found {
+			response = fmt.Sprintf("%s appeared", condition)
+		} else {
+			response = fmt.Sprintf("timed out waiting for %s", condition)
+		}
+	case trajectory.BrowserActionTypeOpenTab:
+		tabID, err := b.OpenTab(action.URL)
+		if err != nil {
+			return "", fmt.Errorf("error opening tab: %w", err)
+		}
+		response = fmt.Sprintf("opened %s", tabID)
+		if action.URL != "" {
+			response = fmt.Sprintf("%s and navigated to %s", response, action.URL)
+		}
+	case trajectory.BrowserActionTypeSwitchTab:
+		if err = b.SwitchTab(action.TabID); err != nil {
+			return "", fmt.Errorf("error switching tab: %w", err)
+		}
+		response = fmt.Sprintf("switched to %s", action.TabID)
+	case trajectory.BrowserActionTypeCloseTab:
+		closedTabID := action.TabID
+		if closedTabID == "" {
+			closedTabID = previousTabID
+		}
+		if err = b.CloseTab(closedTabID); err != nil {
+			return "", fmt.Errorf("error closing tab: %w", err)
+		}
+		response = fmt.Sprintf("closed %s; the active tab is %s", closedTabID, b.ActiveTabID())
+	case trajectory.BrowserActionTypeInspectNetwork:
+		if response, err = b.InspectNetwork(action.URLPattern, action.MaxTokens); err != nil {
+			return "", fmt.Errorf("error inspecting network: %w", err)
+		}
+	case trajectory.BrowserActionTypeExtract:
+		if response, err = b.extractForAction(action.Fields, action.Schema); err != nil {
+			return "", fmt.Errorf("error extracting: %w", err)
+		}
+	case trajectory.BrowserActionTypeHover:
+		if err = b.Hover(action.ID); err != nil {
+			return "", fmt.Errorf("error hovering: %w", err)
+		}
+		response = fmt.Sprintf("hovered over %s", action.ID)
+	case trajectory.BrowserActionTypeDrag:
+		if err = b.Drag(action.FromID, action.ToID); err != nil {
+			return "", fmt.Errorf("error dragging: %w", err)
+		}
+		response = fmt.Sprintf("dragged %s onto %s", action.FromID, action.ToID)
+	case trajectory.BrowserActionTypeClickAt:
+		description, err := b.ClickAt(action.X, action.Y)
+		if err != nil {
+			return "", fmt.Errorf("error clicking at point: %w", err)
+		}
+		response = fmt.Sprintf("clicked at (%g, %g)", action.X, action.Y)
+		if description != "" {
+			response = fmt.Sprintf("%s on %s", response, description)
+		}
 	default:
 		return "", fmt.Errorf("unsupported browser action type: %s", action.Type)
 	}
+	b.waitForDownloads(b.waitOptions.timeoutFor(action.Type))
 	if err := b.updateDisplay(); err != nil {
 		return "", fmt.Errorf("error updating display: %w", err)
 	}
+	// any action can navigate, such as by pressing enter in a form or by a script or redirect that runs later
+	if err := b.checkLocation(); err != nil {
+		return "", err
+	}
+	if activeTabID := b.ActiveTabID(); activeTabID != previousTabID && !isTabActionType(action.Type) {
+		response = fmt.Sprintf("%s; switched to newly opened %s", response, activeTabID)
+	}
+	b.captureScreenshotAfterAction()
 	return response, nil
 }
 
+func isTabActionType(typ trajectory.BrowserActionType) bool {
+	return typ == trajectory.BrowserActionTypeOpenTab || typ == trajectory.BrowserActionTypeSwitchTab || typ == trajectory.BrowserActionTypeCloseTab
+}
+
+// Runs actions in the active tab. Returns ErrDialogOpen if a dialog is open or opens before the actions finish,
+// since the page cannot respond until the dialog is closed.
 func (b *Browser) run(actions ...chromedp.Action) error {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	return chromedp.Run(b.ctx, actions...)
+	dialogs := b.activeTab.dialogs
+	if dialogs.current() != nil {
+		return ErrDialogOpen
+	}
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	defer dialogs.onOpen(cancel)()
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		if dialogs.current() != nil {
+			return ErrDialogOpen
+		}
+		return err
+	}
+	return nil
 }
 
 func (b *Browser) Click(id virtualid.VirtualID) error {
@@ -117,18 +392,22 @@ func (b *Browser) Click(id virtualid.VirtualID) error {
 		return fmt.Errorf("error checking element type for virtual id: %w", err)
 	} else if elementType != ElementTypeButton && elementType != ElementTypeLink {
 		return fmt.Errorf("cannot click element type %s", elementType)
-	} else if err := b.ClickByVirtualID(string(id)); err != nil {
+	} else if err := b.MouseClickByVirtualID(string(id)); err != nil {
 		return fmt.Errorf("error clicking by virtual id: %w", err)
 	}
-	if loaded, err := b.isPageLoaded(); err != nil {
-		log.Println("error checking if page is loaded:", err)
-	} else if !loaded {
-		b.waitForPageLoad()
-	}
+	return b.afterClick(trajectory.BrowserActionTypeClick, previousLocation)
+}
+
+// Waits for the page to settle after a click and checks the page that the click navigated to, if any.
+func (b *Browser) afterClick(actionType trajectory.BrowserActionType, previousLocation string) error {
+	b.waitForPageToSettle(actionType)
 	if err := b.updateDisplay(); err != nil {
 		return fmt.Errorf("error updating display: %w", err)
 	}
 	if previousLocation != b.display.Location {
+		if err := b.checkLocation(); err != nil {
+			return err
+		}
 		if supportsAriaLabels, err := b.DoesSupportAriaLabels(); err != nil {
 			log.Println("error checking if browser supports aria labels:", err)
 		} else if !supportsAriaLabels {
@@ -138,7 +417,8 @@ func (b *Browser) Click(id virtualid.VirtualID) error {
 	return nil
 }
 
-func (b *Browser) SendKeys(id virtualid.VirtualID, keys string) error {
+// Types keys into an input or textarea. The existing content is cleared first unless shouldAppend is true.
+func (b *Browser) SendKeys(id virtualid.VirtualID, keys string, shouldAppend bool) error {
 	if !b.vIDGenerator.IsValidVirtualID(id) {
 		return fmt.Errorf("invalid virtual id: %s", id)
 	} else if keys == "" {
@@ -151,92 +431,288 @@ func (b *Browser) SendKeys(id virtualid.VirtualID, keys string) error {
 		return fmt.Errorf("error checking element type for virtual id: %w", err)
 	} else if elementType != ElementTypeInput && elementType != ElementTypeTextArea {
 		return fmt.Errorf("cannot send keys to element type %s", elementType)
-	} else if err := b.SendTextByVirtualID(string(id), keys); err != nil {
+	} else if err := b.SendTextByVirtualID(string(id), keys, !shouldAppend); err != nil {
 		return fmt.Errorf("error sending text by virtual id: %w", err)
 	}
-	if loaded, err := b.isPageLoaded(); err != nil {
-		log.Println("error checking if page is loaded:", err)
-	} else if !loaded {
-		b.waitForPageLoad()
+	b.waitForPageToSettle(trajectory.BrowserActionTypeSendKeys)
+	return b.updateDisplay()
+}
+
+// Presses a key combination such as "Enter" or "Shift+Tab". If an id is given, the element is focused first;
+// otherwise, the key is sent to the currently focused element.
+func (b *Browser) PressKey(id virtualid.VirtualID, key string) error {
+	if _, _, err := ParseKeyCombination(key); err != nil {
+		return fmt.Errorf("invalid key %s: %w", key, err)
+	}
+	if id == "" {
+		if err := b.PressKeyCombination(key); err != nil {
+			return fmt.Errorf("error pressing key combination: %w", err)
+		}
+	} else if !b.vIDGenerator.IsValidVirtualID(id) {
+		return fmt.Errorf("invalid virtual id: %s", id)
+	} else if exists, err := b.DoesVirtualIDExist(string(id)); err != nil {
+		return fmt.Errorf("error checking if virtual id exists: %w", err)
+	} else if !exists {
+		return fmt.Errorf("virtual id does not exist: %s", id)
+	} else if err := b.PressKeyCombinationByVirtualID(string(id), key); err != nil {
+		return fmt.Errorf("error pressing key combination by virtual id: %w", err)
 	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypePressKey)
+	return b.updateDisplay()
+}
+
+// Selects an option in a select element by value or by visible label. Returns the label of the selected option.
+func (b *Browser) SelectOption(id virtualid.VirtualID, option string) (string, error) {
+	var selected string
+	if !b.vIDGenerator.IsValidVirtualID(id) {
+		return "", fmt.Errorf("invalid virtual id: %s", id)
+	} else if option == "" {
+		return "", errors.New("option cannot be empty")
+	} else if exists, err := b.DoesVirtualIDExist(string(id)); err != nil {
+		return "", fmt.Errorf("error checking if virtual id exists: %w", err)
+	} else if !exists {
+		return "", fmt.Errorf("virtual id does not exist: %s", id)
+	} else if elementType, err := b.CheckElementTypeForVirtualID(string(id)); err != nil {
+		return "", fmt.Errorf("error checking element type for virtual id: %w", err)
+	} else if elementType != ElementTypeSelect {
+		return "", fmt.Errorf("cannot select an option in element type %s", elementType)
+	} else if selected, err = b.SelectOptionByVirtualID(string(id), option); err != nil {
+		return "", fmt.Errorf("error selecting option by virtual id: %w", err)
+	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypeSelectOption)
+	if err := b.updateDisplay(); err != nil {
+		return "", fmt.Errorf("error updating display: %w", err)
+	}
+	return selected, nil
+}
+
+// Toggles a checkbox or selects a radio button. Returns whether the element is checked afterwards.
+func (b *Browser) Toggle(id virtualid.VirtualID) (bool, error) {
+	var checked bool
+	if !b.vIDGenerator.IsValidVirtualID(id) {
+		return false, fmt.Errorf("invalid virtual id: %s", id)
+	} else if exists, err := b.DoesVirtualIDExist(string(id)); err != nil {
+		return false, fmt.Errorf("error checking if virtual id exists: %w", err)
+	} else if !exists {
+		return false, fmt.Errorf("virtual id does not exist: %s", id)
+	} else if elementType, err := b.CheckElementTypeForVirtualID(string(id)); err != nil {
+		return false, fmt.Errorf("error checking element type for virtual id: %w", err)
+	} else if elementType != ElementTypeCheckbox && elementType != ElementTypeRadio {
+		return false, fmt.Errorf("cannot toggle element type %s", elementType)
+	} else if checked, err = b.ToggleByVirtualID(string(id)); err != nil {
+		return false, fmt.Errorf("error toggling by virtual id: %w", err)
+	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypeToggle)
+	if err := b.updateDisplay(); err != nil {
+		return false, fmt.Errorf("error updating display: %w", err)
+	}
+	return checked, nil
+}
+
+func (b *Browser) Scroll(direction ScrollDirection) error {
+	if direction != ScrollDirectionUp && direction != ScrollDirectionDown {
+		return fmt.Errorf("invalid scroll direction: %s", direction)
+	} else if err := b.ScrollPage(direction); err != nil {
+		return fmt.Errorf("error scrolling page %s: %w", direction, err)
+	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypeScroll)
+	return b.updateDisplay()
+}
+
+// Moves the mouse over an element, such as to open a menu that only appears on hover.
+func (b *Browser) Hover(id virtualid.VirtualID) error {
+	if !b.vIDGenerator.IsValidVirtualID(id) {
+		return fmt.Errorf("invalid virtual id: %s", id)
+	} else if exists, err := b.DoesVirtualIDExist(string(id)); err != nil {
+		return fmt.Errorf("error checking if virtual id exists: %w", err)
+	} else if !exists {
+		return fmt.Errorf("virtual id does not exist: %s", id)
+	} else if err := b.HoverByVirtualID(string(id)); err != nil {
+		return fmt.Errorf("error hovering by virtual id: %w", err)
+	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypeHover)
+	return b.updateDisplay()
+}
+
+// Drags an element with the mouse and drops it onto another element.
+func (b *Browser) Drag(fromID virtualid.VirtualID, toID virtualid.VirtualID) error {
+	if fromID == toID {
+		return errors.New("cannot drag an element onto itself")
+	}
+	for _, id := range []virtualid.VirtualID{fromID, toID} {
+		if !b.vIDGenerator.IsValidVirtualID(id) {
+			return fmt.Errorf("invalid virtual id: %s", id)
+		} else if exists, err := b.DoesVirtualIDExist(string(id)); err != nil {
+			return fmt.Errorf("error checking if virtual id exists: %w", err)
+		} else if !exists {
+			return fmt.Errorf("virtual id does not exist: %s", id)
+		}
+	}
+	if err := b.DragByVirtualIDs(string(fromID), string(toID)); err != nil {
+		return fmt.Errorf("error dragging by virtual ids: %w", err)
+	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypeDrag)
+	return b.updateDisplay()
+}
+
+func (b *Browser) ScrollTo(id virtualid.VirtualID) error {
+	if !b.vIDGenerator.IsValidVirtualID(id) {
+		return fmt.Errorf("invalid virtual id: %s", id)
+	} else if exists, err := b.DoesVirtualIDExist(string(id)); err != nil {
+		return fmt.Errorf("error checking if virtual id exists: %w", err)
+	} else if !exists {
+		return fmt.Errorf("virtual id does not exist: %s", id)
+	} else if err := b.ScrollIntoViewByVirtualID(string(id)); err != nil {
+		return fmt.Errorf("error scrolling into view by virtual id: %w", err)
+	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypeScroll)
 	return b.updateDisplay()
 }
 
 func (b *Browser) Navigate(URL string) error {
-	u, err := GetCanonicalURL(URL)
+	// the url is checked before it is canonicalized, which may send requests to it; its syntax is fixed first so
+	// that urls without a scheme are checked by their host
+	if syntactic, err := NewSyntaxURLCanonicalizer().Canonicalize(URL); err != nil {
+		return fmt.Errorf("error canonicalizing url: %w", err)
+	} else if err := b.checkNavigation(syntactic); err != nil {
+		return err
+	}
+	u, err := b.urlCanonicalizer.Canonicalize(URL)
 	if err != nil {
-		return fmt.Errorf("error ensuring scheme: %w", err)
+		return fmt.Errorf("error canonicalizing url: %w", err)
 	}
 	valid, err := IsValidURL(u)
 	if !valid {
 		return fmt.Errorf("invalid url %s: %w", u, err)
 	}
+	if err := b.checkNavigation(u); err != nil {
+		return err
+	}
 	err = b.run(chromedp.Navigate(u))
 	if err != nil {
 		return fmt.Errorf("error navigating to %s: %w", u, err)
 	}
-	if loaded, err := b.isPageLoaded(); err != nil {
-		log.Println("error checking if page is loaded:", err)
-	} else if !loaded {
-		b.waitForPageLoad()
+	b.waitForPageToSettle(trajectory.BrowserActionTypeNavigate)
+	if supportsAriaLabels, err := b.DoesSupportAriaLabels(); err != nil {
+		log.Println("error checking if browser supports aria labels:", err)
+	} else if !supportsAriaLabels {
+		log.Println("warning: this page does not support aria labels")
+	}
+	if err := b.updateDisplay(); err != nil {
+		return err
 	}
+	// the page may have redirected to a blocked url
+	return b.checkLocation()
+}
+
+func (b *Browser) GoBack() error {
+	if err := b.run(chromedp.NavigateBack()); err != nil {
+		return fmt.Errorf("error navigating back (there may be no previous page): %w", err)
+	}
+	return b.afterHistoryNavigation(trajectory.BrowserActionTypeGoBack)
+}
+
+func (b *Browser) GoForward() error {
+	if err := b.run(chromedp.NavigateForward()); err != nil {
+		return fmt.Errorf("error navigating forward (there may be no next page): %w", err)
+	}
+	return b.afterHistoryNavigation(trajectory.BrowserActionTypeGoForward)
+}
+
+func (b *Browser) Reload() error {
+	if err := b.run(chromedp.Reload()); err != nil {
+		return fmt.Errorf("error reloading: %w", err)
+	}
+	return b.afterHistoryNavigation(trajectory.BrowserActionTypeReload)
+}
+
+func (b *Browser) afterHistoryNavigation(actionType trajectory.BrowserActionType) error {
+	b.waitForPageToSettle(actionType)
 	if supportsAriaLabels, err := b.DoesSupportAriaLabels(); err != nil {
 		log.Println("error checking if browser supports aria labels:", err)
 	} else if !supportsAriaLabels {
 		log.Println("warning: this page does not support aria labels")
 	}
-	return b.updateDisplay()
+	if err := b.updateDisplay(); err != nil {
+		return err
+	}
+	return b.checkLocation()
 }
 
+// Assigns virtual ids to the visible interactive elements that do not have one yet. The ids are generated by
+// the virtual id generator of the browser from the fingerprints of the elements.
 func (b *Browser) addVirtualIDs() error {
 	existingVirtualIDs, err := b.GetAllVisibleVirtualIDs()
 	if err != nil {
 		log.Println("error getting existing virtual ids:", err)
 		return nil
 	}
-	// TODO: invoke custom vID generator
-	js := fmt.Sprintf(`function addDataVidAttribute(excludeIDs) {
-	const reservedIDs = {};
-	excludeIDs.forEach(id => reservedIDs[id] = true);
-	const elements = document.querySelectorAll('button, input, a, textarea');
-	let counter = 0;
-	elements.forEach(element => {
-		if (element.offsetParent !== null && !element.hasAttribute('data-vid')) {
-			while (reservedIDs["vid-" + counter.toString()]) {
-				counter++;
-			}
-			element.setAttribute('data-vid', "vid-" + counter.toString());
-			counter++;
-		}
-	});
-}
-addDataVidAttribute(%s);`, "["+strings.Join(slicesx.Map(existingVirtualIDs, func(virtualID string, _ int) string {
-		return fmt.Sprintf(`"%s"`, virtualID)
-	}), ", ")+"]")
-	return b.run(chromedp.Evaluate(js, nil))
+	elements, err := b.markElementsWithoutVirtualIDs()
+	if err != nil {
+		return fmt.Errorf("error finding elements without virtual ids: %w", err)
+	}
+	taken := make(map[virtualid.VirtualID]bool)
+	for _, id := range existingVirtualIDs {
+		taken[virtualid.VirtualID(id)] = true
+	}
+	isTaken := func(id virtualid.VirtualID) bool {
+		return taken[id]
+	}
+	newVirtualIDs := make([]string, len(elements))
+	for i, element := range elements {
+		id := b.vIDGenerator.Generate(element, isTaken)
+		taken[id] = true
+		newVirtualIDs[i] = string(id)
+	}
+	return b.assignMarkedVirtualIDs(newVirtualIDs)
 }
 
 func (b *Browser) Render(lang language.Language) (content string, err error) {
+	if err := b.syncTabs(); err != nil {
+		log.Println("error syncing tabs:", err)
+	}
+	if dialog := b.GetOpenDialog(); dialog != nil {
+		// the page cannot be rendered while it is blocked, so the last render is shown with the dialog
+		if err := b.updateDisplay(); err != nil {
+			return "", err
+		}
+		return b.display.MD, nil
+	}
 	if location, err := b.getLocation(); err != nil {
 		return "", fmt.Errorf("error getting location: %w", err)
 	} else if translator, ok := b.translators[lang]; !ok {
 		return "", fmt.Errorf("unsupported language: %s", lang)
 	} else if err := b.addVirtualIDs(); err != nil {
 		return "", err
-	} else if html, err := b.getHTML(); err != nil {
+	} else if html, err := b.getRenderableHTML(); err != nil {
 		return "", fmt.Errorf("error getting html for location %s: %w", location, err)
-	} else if translation, err := translator.Translate(html); err != nil {
+	} else if input, err := b.getTranslatorInput(lang, html); err != nil {
+		return "", fmt.Errorf("error getting %s input for location %s: %w", lang, location, err)
+	} else if translation, err := translator.Translate(input); err != nil {
 		return "", fmt.Errorf("error translating html to %s for location %s: %w", lang, location, err)
 	} else {
+		tabs := b.getTabDisplays()
+		b.pageContent = translation
+		translation = renderDisplay(tabs, b.emulationProfile, nil, translation)
 		b.display = &BrowserDisplay{
-			HTML:     html,
-			MD:       translation,
-			Location: location,
+			HTML:      html,
+			MD:        translation,
+			Location:  location,
+			Tabs:      tabs,
+			Emulation: b.emulationProfile,
 		}
 		return translation, nil
 	}
 }
 
+// Returns the input of the translator of a language. Most translators translate the html of the page.
+func (b *Browser) getTranslatorInput(lang language.Language, html string) (string, error) {
+	if lang == language.LanguageAXTree {
+		return b.getAXTree()
+	}
+	return html, nil
+}
+
 func (b *Browser) getLocation() (string, error) {
 	var url string
 	if err := b.run(chromedp.Location(&url)); err != nil {
@@ -246,14 +722,15 @@ func (b *Browser) getLocation() (string, error) {
 	}
 }
 
+// Returns the html of the active tab, including the documents of iframes and open shadow roots.
 func (b *Browser) getHTML() (string, error) {
 	var html string
 	if err := chromedp.Run(b.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
-		node, err := dom.GetDocument().Do(ctx)
+		node, err := dom.GetDocument().WithDepth(-1).WithPierce(true).Do(ctx)
 		if err != nil {
 			return err
 		}
-		html, err = dom.GetOuterHTML().WithNodeID(node.NodeID).Do(ctx)
+		html, err = renderDOMNode(node)
 		return err
 	})); err != nil {
 		return "", err
@@ -262,6 +739,37 @@ func (b *Browser) getHTML() (string, error) {
 	}
 }
 
+// Returns the html that should be translated for the current render mode.
+func (b *Browser) getRenderableHTML() (string, error) {
+	if err := b.syncFormState(); err != nil {
+		log.Println("error syncing form state:", err)
+	}
+	if b.renderMode != RenderModeViewport {
+		return b.getHTML()
+	}
+	if err := b.addViewportMarkers(); err != nil {
+		return "", fmt.Errorf("error adding viewport markers: %w", err)
+	}
+	defer func() {
+		if err := b.removeViewportMarkers(); err != nil {
+			log.Println("error removing viewport markers:", err)
+		}
+	}()
+	return b.getHTML()
+}
+
+func (b *Browser) GetRenderMode() RenderMode {
+	return b.renderMode
+}
+
+func (b *Browser) SetRenderMode(mode RenderMode) error {
+	if mode != RenderModeFull && mode != RenderModeViewport {
+		return fmt.Errorf("unsupported render mode: %s", mode)
+	}
+	b.renderMode = mode
+	return nil
+}
+
 func (b *Browser) GetDisplay() *BrowserDisplay {
 	return b.display
 }
@@ -278,15 +786,14 @@ func (b *Browser) RunHeadful(ctx context.Context) error {
 	if !b.isRunningHeadless {
 		log.Println("requested to run the browser in headful mode but this browser is already running in headful mode")
 		return nil
+	} else if b.isRemote {
+		return errors.New("cannot run a remote browser in headful mode; it runs in headless mode because it was launched that way")
 	}
-	log.Println("running the browser in headful mode; warning: you will lose all non-location state from the current browser")
-	newOps := append(b.options, BrowserOptionHeadful)
-	newBrowserCtx, newBrowserCancelFunc := newBrowser(ctx, newOps...)
-	b.cancel()
-	b.ctx = newBrowserCtx
-	b.cancel = newBrowserCancelFunc
-	if err := b.Navigate(b.display.Location); err != nil {
-		return fmt.Errorf("error navigating to current location %s: %w", b.display.Location, err)
+	log.Println("running the browser in headful mode; cookies, storage, and the location of the active tab are carried over")
+	config := b.launchConfig.clone()
+	config.Headful = true
+	if err := b.relaunch(ctx, config); err != nil {
+		return err
 	}
 	b.isRunningHeadless = false
 	return nil
@@ -296,60 +803,106 @@ func (b *Browser) RunHeadless(ctx context.Context) error {
 	if b.isRunningHeadless {
 		log.Println("requested to run the browser in headless mode but this browser is already running in headless mode")
 		return nil
+	} else if b.isRemote {
+		return errors.New("cannot run a remote browser in headless mode; it runs in headful mode because it was launched that way")
 	}
-	log.Println("running the browser in headless mode; warning: you will lose all non-location state from the current browser")
-	newOps := slicesx.Filter(b.options, func(option BrowserOption, _ int) bool {
-		return option != BrowserOptionHeadful
-	})
-	newBrowserCtx, newBrowserCancelFunc := newBrowser(ctx, newOps...)
-	b.cancel()
-	b.ctx = newBrowserCtx
-	b.cancel = newBrowserCancelFunc
-	if err := b.Navigate(b.display.Location); err != nil {
-		return fmt.Errorf("error navigating to current location %s: %w", b.display.Location, err)
+	log.Println("running the browser in headless mode; cookies, storage, and the location of the active tab are carried over")
+	config := b.launchConfig.clone()
+	config.Headful = false
+	if err := b.relaunch(ctx, config); err != nil {
+		return err
 	}
 	b.isRunningHeadless = true
 	return nil
 }
 
-func buildOptions(options ...BrowserOption) []func(*chromedp.ExecAllocator) {
-	ops := chromedp.DefaultExecAllocatorOptions[:]
-	for _, option := range options {
-		switch option {
-		case BrowserOptionHeadful:
-			ops = append(ops, chromedp.Flag("headless", false))
-		case BrowserOptionAttemptToDisableAutomationMessage:
-			ops = append(ops, chromedp.UserAgent("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"))
-			ops = append(ops, chromedp.Flag("enable-automation", false))
-		default:
-		}
+// Replaces the browser with a newly launched one and carries the cookies, storage, and location of the active
+// tab over to it. Other tabs are not reopened.
+func (b *Browser) relaunch(ctx context.Context, config *LaunchConfig) error {
+	state, err := b.captureState()
+	if err != nil {
+		log.Println("error capturing browser state; only the location will be carried over:", err)
+		state = &StorageState{URL: b.display.Location}
 	}
-	return ops
+	newBrowserCtx, newBrowserCancelFunc, err := newBrowser(ctx, b.downloads, config)
+	if err != nil {
+		return err
+	}
+	b.cancel()
+	b.launchConfig = config
+	b.resetTabs(newBrowserCtx, newBrowserCancelFunc)
+	if err := b.restoreState(state); err != nil {
+		log.Println("error restoring browser state:", err)
+	}
+	if err := b.Navigate(state.URL); err != nil {
+		return fmt.Errorf("error navigating to current location %s: %w", state.URL, err)
+	}
+	return nil
 }
 
-func newBrowser(ctx context.Context, options ...BrowserOption) (browserCtx context.Context, cancelFunc context.CancelFunc) {
-	ops := buildOptions(options...)
+func newBrowser(ctx context.Context, downloads *downloadTracker, config *LaunchConfig) (browserCtx context.Context, cancelFunc context.CancelFunc, err error) {
+	ops, err := buildOptions(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error building launch options: %w", err)
+	}
 	parentCtx, _ := chromedp.NewExecAllocator(ctx, ops...)
 	browserCtx, cancel := chromedp.NewContext(parentCtx)
-	return browserCtx, cancel
+	chromedp.ListenBrowser(browserCtx, downloads.handleEvent)
+	if err := chromedp.Run(browserCtx, downloads.configure()); err != nil {
+		log.Println("error configuring downloads:", err)
+	}
+	return browserCtx, cancel, nil
 }
 
-func NewBrowser(ctx context.Context, options ...BrowserOption) *Browser {
-	isRunningHeadless := !slicesx.Contains(options, BrowserOptionHeadful)
-	vIDGenerator := virtualid.NewIncrIntVirtualIDGenerator()
+// Creates a browser that is launched with the launch config, or the zero LaunchConfig if it is nil, and that may
+// only visit the pages permitted by the navigation policy, or any page if it is nil.
+func NewBrowser(ctx context.Context, policy *NavigationPolicy, config *LaunchConfig) (*Browser, error) {
+	b, err := newBrowserWithDefaults(policy, config)
+	if err != nil {
+		return nil, err
+	}
+	b.isRunningHeadless = !b.launchConfig.Headful
+	browserCtx, cancel, err := newBrowser(ctx, b.downloads, b.launchConfig)
+	if err != nil {
+		return nil, err
+	}
+	b.resetTabs(browserCtx, cancel)
+	return b, nil
+}
+
+// Creates a browser without tabs, which must be added with resetTabs.
+func newBrowserWithDefaults(policy *NavigationPolicy, config *LaunchConfig) (*Browser, error) {
+	if config == nil {
+		config = &LaunchConfig{}
+	} else if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid launch config: %w", err)
+	}
+	vIDGenerator := virtualid.NewFingerprintVirtualIDGenerator()
 	htmlToMDTranslator := html2md.NewHTML2MDTranslator(nil)
+	axToMDTranslator := ax2md.NewAX2MDTranslator(nil)
 	translatorMap := map[language.Language]translators.Translator{
-		language.LanguageMD: htmlToMDTranslator,
+		language.LanguageMD:     htmlToMDTranslator,
+		language.LanguageAXTree: axToMDTranslator,
 	}
-	browserCtx, cancel := newBrowser(ctx, options...)
-	return &Browser{
-		mu:                &sync.Mutex{},
-		ctx:               browserCtx,
-		cancel:            cancel,
-		options:           options,
-		vIDGenerator:      vIDGenerator,
-		translators:       translatorMap,
-		display:           &BrowserDisplay{},
-		isRunningHeadless: isRunningHeadless,
+	interceptor, err := newRequestInterceptor(config, policy)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring request interception: %w", err)
 	}
+	return &Browser{
+		mu:               &sync.Mutex{},
+		launchConfig:     config.clone(),
+		vIDGenerator:     vIDGenerator,
+		translators:      translatorMap,
+		display:          &BrowserDisplay{},
+		renderMode:       RenderModeFull,
+		waitOptions:      DefaultWaitOptions(),
+		screenshotMode:   ScreenshotModeNone,
+		downloads:        newDownloadTracker(defaultArtifactsDirectory()),
+		interceptor:      interceptor,
+		dialogPolicy:     DialogPolicyNone,
+		navigationPolicy: policy,
+		urlCanonicalizer: NewSyntaxURLCanonicalizer(),
+		console:          newConsoleTracker(),
+		networkLog:       newNetworkRecorder(),
+	}, nil
 }
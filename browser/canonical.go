@@ -0,0 +1,171 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Turns the url that was given to navigate, which may be missing its scheme, into the url that is visited.
+type URLCanonicalizer interface {
+	Canonicalize(URL string) (string, error)
+}
+
+type URLCanonicalizationMode string
+
+const (
+	// Only fixes the syntax of the url, without any network requests.
+	URLCanonicalizationModeSyntax URLCanonicalizationMode = "syntax"
+	// Fixes the syntax of the url and then probes it to follow redirects and prefer the www. version of the host.
+	URLCanonicalizationModeProbe URLCanonicalizationMode = "probe"
+)
+
+// Schemes of urls that have no authority, such as "data:text/html,...", and so are not followed by "//".
+var opaqueSchemes = map[string]bool{
+	"about":      true,
+	"blob":       true,
+	"data":       true,
+	"javascript": true,
+	"mailto":     true,
+}
+
+type SyntaxURLCanonicalizer struct{}
+
+// A URLCanonicalizer that never makes network requests. Urls with a scheme are kept as they are, absolute paths
+// become file: urls, local and intranet hosts get http://, and all other hosts get https://.
+func NewSyntaxURLCanonicalizer() URLCanonicalizer {
+	return &SyntaxURLCanonicalizer{}
+}
+
+func (c *SyntaxURLCanonicalizer) Canonicalize(URL string) (string, error) {
+	URL = strings.TrimSpace(URL)
+	if URL == "" {
+		return "", errors.New("url cannot be empty")
+	} else if hasScheme(URL) {
+		return URL, nil
+	} else if filepath.IsAbs(URL) {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(URL)}).String(), nil
+	}
+	u, err := url.Parse("http://" + URL)
+	if err != nil {
+		return "", fmt.Errorf("error parsing url: %w", err)
+	}
+	if isLocalHost(u.Hostname()) {
+		return u.String(), nil
+	}
+	u.Scheme = "https"
+	return u.String(), nil
+}
+
+// Reports whether the url starts with a scheme. "localhost:8080" and "example.com:443/path" do not, although
+// they parse as urls with the schemes "localhost" and "example.com".
+func hasScheme(URL string) bool {
+	u, err := url.Parse(URL)
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	rest := URL[len(u.Scheme)+1:]
+	return strings.HasPrefix(rest, "//") || opaqueSchemes[strings.ToLower(u.Scheme)]
+}
+
+// Reports whether the host is the local machine or an intranet host, which are usually served over plain http.
+func isLocalHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") {
+		return true
+	} else if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified()
+	}
+	return !strings.Contains(host, ".")
+}
+
+type ProbeURLCanonicalizer struct {
+	client *http.Client
+	syntax URLCanonicalizer
+}
+
+const defaultProbeTimeout = 5 * time.Second
+
+// A URLCanonicalizer that fixes the syntax of the url and then sends HEAD requests to public http and https urls
+// to follow redirects and to prefer the www. version of the host if it exists. If a probe fails, the url is
+// used as it is. Uses a client with a short timeout if client is nil.
+func NewProbeURLCanonicalizer(client *http.Client) URLCanonicalizer {
+	if client == nil {
+		client = &http.Client{Timeout: defaultProbeTimeout}
+	}
+	return &ProbeURLCanonicalizer{
+		client: client,
+		syntax: NewSyntaxURLCanonicalizer(),
+	}
+}
+
+func (c *ProbeURLCanonicalizer) Canonicalize(URL string) (string, error) {
+	URL, err := c.syntax.Canonicalize(URL)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || isLocalHost(u.Hostname()) {
+		return URL, nil
+	}
+	resp, err := c.client.Head(URL)
+	if err != nil {
+		log.Printf("error probing %s; it will be used as it is: %v", URL, err)
+		return URL, nil
+	}
+	resp.Body.Close()
+	if redirected := resp.Request.URL.String(); redirected != URL {
+		return redirected, nil
+	}
+	if !strings.HasPrefix(u.Host, "www.") {
+		wwwVersion := strings.Replace(URL, "://", "://www.", 1)
+		resp, err := c.client.Head(wwwVersion)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+				return wwwVersion, nil
+			}
+		}
+	}
+	return URL, nil
+}
+
+func URLCanonicalizerByMode(mode URLCanonicalizationMode) (URLCanonicalizer, error) {
+	switch mode {
+	case URLCanonicalizationModeSyntax:
+		return NewSyntaxURLCanonicalizer(), nil
+	case URLCanonicalizationModeProbe:
+		return NewProbeURLCanonicalizer(nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported url canonicalization mode: %s", mode)
+	}
+}
+
+// Sets how urls are canonicalized before they are visited. Defaults to URLCanonicalizationModeSyntax.
+func (b *Browser) SetURLCanonicalizationMode(mode URLCanonicalizationMode) error {
+	canonicalizer, err := URLCanonicalizerByMode(mode)
+	if err != nil {
+		return err
+	}
+	b.urlCanonicalizer = canonicalizer
+	return nil
+}
+
+func (b *Browser) SetURLCanonicalizer(canonicalizer URLCanonicalizer) {
+	if canonicalizer == nil {
+		canonicalizer = NewSyntaxURLCanonicalizer()
+	}
+	b.urlCanonicalizer = canonicalizer
+}
+
+// Canonicalizes the url by following redirects and preferring the www. version of the host.
+// Note: This makes network requests; use a SyntaxURLCanonicalizer to canonicalize urls offline.
+func GetCanonicalURL(URL string) (string, error) {
+	return NewProbeURLCanonicalizer(nil).Canonicalize(URL)
+}
@@ -0,0 +1,77 @@
+package browser
+
+import "testing"
+
+func TestSyntaxURLCanonicalizer(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"example.com", "https://example.com", false},
+		{"  example.com/path?q=1  ", "https://example.com/path?q=1", false},
+		{"example.com:443/path", "https://example.com:443/path", false},
+		{"localhost", "http://localhost", false},
+		{"localhost:8080", "http://localhost:8080", false},
+		{"localhost:3000/api", "http://localhost:3000/api", false},
+		{"app.localhost:5173", "http://app.localhost:5173", false},
+		{"printer.local", "http://printer.local", false},
+		{"127.0.0.1:8000", "http://127.0.0.1:8000", false},
+		{"192.168.1.1", "http://192.168.1.1", false},
+		{"10.0.0.5/admin", "http://10.0.0.5/admin", false},
+		{"172.16.0.1", "http://172.16.0.1", false},
+		{"[::1]:8080", "http://[::1]:8080", false},
+		{"8.8.8.8", "https://8.8.8.8", false},
+		{"intranet", "http://intranet", false},
+		{"wiki/page", "http://wiki/page", false},
+		{"http://example.com", "http://example.com", false},
+		{"https://example.com/a", "https://example.com/a", false},
+		{"HTTPS://Example.com", "HTTPS://Example.com", false},
+		{"ftp://files.example.com", "ftp://files.example.com", false},
+		{"file:///tmp/index.html", "file:///tmp/index.html", false},
+		{"/tmp/index.html", "file:///tmp/index.html", false},
+		{"data:text/html,<h1>hi</h1>", "data:text/html,<h1>hi</h1>", false},
+		{"about:blank", "about:blank", false},
+		{"mailto:someone@example.com", "mailto:someone@example.com", false},
+		{"", "", true},
+		{"   ", "", true},
+		{"example.com:port", "", true},
+	}
+	canonicalizer := NewSyntaxURLCanonicalizer()
+	for _, test := range tests {
+		got, err := canonicalizer.Canonicalize(test.url)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Canonicalize(%q) error = %v, want error %t", test.url, err, test.wantErr)
+		} else if got != test.want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
+
+func TestIsLocalHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost", true},
+		{"LOCALHOST", true},
+		{"api.localhost", true},
+		{"nas.local", true},
+		{"intranet", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"0.0.0.0", true},
+		{"10.1.2.3", true},
+		{"192.168.0.10", true},
+		{"fd00::1", true},
+		{"example.com", false},
+		{"localhost.example.com", false},
+		{"1.1.1.1", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, test := range tests {
+		if got := isLocalHost(test.host); got != test.want {
+			t.Errorf("isLocalHost(%q) = %t, want %t", test.host, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/runtime"
+)
+
+type ConsoleLevel string
+
+const (
+	ConsoleLevelLog     ConsoleLevel = "log"
+	ConsoleLevelInfo    ConsoleLevel = "info"
+	ConsoleLevelDebug   ConsoleLevel = "debug"
+	ConsoleLevelWarning ConsoleLevel = "warning"
+	ConsoleLevelError   ConsoleLevel = "error"
+	// An uncaught JavaScript exception or unhandled promise rejection.
+	ConsoleLevelException ConsoleLevel = "exception"
+)
+
+// A message that a page logged to the console, or an uncaught exception that a page threw.
+type ConsoleMessage struct {
+	Time  time.Time
+	TabID string
+	Level ConsoleLevel
+	Text  string
+	// The script and line that logged the message, if known.
+	URL  string
+	Line int64
+}
+
+func (m *ConsoleMessage) String() string {
+	text := fmt.Sprintf("[%s] %s %s: %s", m.Time.Format(time.RFC3339), m.TabID, m.Level, m.Text)
+	if source := m.source(); source != "" {
+		text = fmt.Sprintf("%s (%s)", text, source)
+	}
+	return text
+}
+
+func (m *ConsoleMessage) source() string {
+	if m.URL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", m.URL, m.Line)
+}
+
+// Whether the message is an error or an exception, which are summarized in observations. Warnings are only
+// logged, since most pages log some that do not matter to the agent.
+func (m *ConsoleMessage) isProblem() bool {
+	return m.Level == ConsoleLevelError || m.Level == ConsoleLevelException
+}
+
+// The most console messages that are kept for the session log; older messages are dropped and counted.
+const maxConsoleLogSize = 10000
+
+// The most problems that are described in the summary of an action, and the most characters of each.
+const (
+	maxConsoleSummaryMessages   = 3
+	maxConsoleSummaryTextLength = 200
+)
+
+// Buffers the console messages and exceptions of all tabs through CDP Runtime events. The messages of the
+// current action are summarized in its observation, and all messages are kept for the session log.
+type consoleTracker struct {
+	mu     sync.Mutex
+	action []*ConsoleMessage
+	log    []*ConsoleMessage
+	// the number of messages that were dropped from the log because it was full
+	dropped int
+}
+
+func newConsoleTracker() *consoleTracker {
+	return &consoleTracker{}
+}
+
+func (c *consoleTracker) handleEvent(t *tab, ev interface{}) {
+	var message *ConsoleMessage
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		message = &ConsoleMessage{
+			Level: consoleLevel(ev.Type),
+			Text:  formatConsoleArgs(ev.Args),
+		}
+		if ev.StackTrace != nil && len(ev.StackTrace.CallFrames) > 0 {
+			frame := ev.StackTrace.CallFrames[0]
+			message.URL = frame.URL
+			message.Line = frame.LineNumber + 1
+		}
+	case *runtime.EventExceptionThrown:
+		details := ev.ExceptionDetails
+		message = &ConsoleMessage{
+			Level: ConsoleLevelException,
+			Text:  details.Text,
+			URL:   details.URL,
+			Line:  details.LineNumber + 1,
+		}
+		if details.Exception != nil && details.Exception.Description != "" {
+			// the description is the message of the error followed by its stack
+			message.Text, _, _ = strings.Cut(details.Exception.Description, "\n")
+		}
+	default:
+		return
+	}
+	message.Time = time.Now()
+	message.TabID = t.id
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.action = append(c.action, message)
+	c.log = append(c.log, message)
+	if len(c.log) > maxConsoleLogSize {
+		c.dropped += len(c.log) - maxConsoleLogSize
+		c.log = c.log[len(c.log)-maxConsoleLogSize:]
+	}
+}
+
+func consoleLevel(typ runtime.APIType) ConsoleLevel {
+	switch typ {
+	case runtime.APITypeError, runtime.APITypeAssert:
+		return ConsoleLevelError
+	case runtime.APITypeWarning:
+		return ConsoleLevelWarning
+	case runtime.APITypeInfo:
+		return ConsoleLevelInfo
+	case runtime.APITypeDebug:
+		return ConsoleLevelDebug
+	default:
+		return ConsoleLevelLog
+	}
+}
+
+// Formats the arguments of a console call the way the DevTools console shows them, separated by spaces.
+func formatConsoleArgs(args []*runtime.RemoteObject) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg.Type == runtime.TypeString:
+			var s string
+			if err := json.Unmarshal(arg.Value, &s); err == nil {
+				parts[i] = s
+				continue
+			}
+			parts[i] = string(arg.Value)
+		case arg.Type == runtime.TypeUndefined:
+			parts[i] = "undefined"
+		case arg.UnserializableValue != "":
+			parts[i] = string(arg.UnserializableValue)
+		case len(arg.Value) > 0:
+			parts[i] = string(arg.Value)
+		default:
+			parts[i] = arg.Description
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Clears the messages of the previous action.
+func (c *consoleTracker) beginAction() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.action = nil
+}
+
+// Returns a short description of the errors and exceptions that were logged during the current action, such as
+// "the page logged 1 exception: TypeError: x is not a function (app.js:3)", or "" if there were none.
+func (c *consoleTracker) summarizeAction() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[ConsoleLevel]int)
+	problems := []*ConsoleMessage{}
+	for _, message := range c.action {
+		if message.isProblem() {
+			counts[message.Level]++
+			problems = append(problems, message)
+		}
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+	tallies := []string{}
+	for _, level := range []ConsoleLevel{ConsoleLevelException, ConsoleLevelError} {
+		if n := counts[level]; n == 1 {
+			tallies = append(tallies, fmt.Sprintf("1 %s", level))
+		} else if n > 1 {
+			tallies = append(tallies, fmt.Sprintf("%d %ss", n, level))
+		}
+	}
+	descriptions := []string{}
+	for i, message := range problems {
+		if i == maxConsoleSummaryMessages {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(problems)-i))
+			break
+		}
+		text := message.Text
+		if runes := []rune(text); len(runes) > maxConsoleSummaryTextLength {
+			text = string(runes[:maxConsoleSummaryTextLength]) + "..."
+		}
+		if source := message.source(); source != "" {
+			text = fmt.Sprintf("%s (%s)", text, source)
+		}
+		descriptions = append(descriptions, text)
+	}
+	return fmt.Sprintf("the page logged %s: %s", strings.Join(tallies, " and "), strings.Join(descriptions, "; "))
+}
+
+// Returns the console messages and exceptions of the session, oldest first. Only the last 10000 messages are
+// kept; see GetDroppedConsoleMessageCount.
+func (b *Browser) GetConsoleLog() []*ConsoleMessage {
+	b.console.mu.Lock()
+	defer b.console.mu.Unlock()
+	return append([]*ConsoleMessage{}, b.console.log...)
+}
+
+// Returns the number of console messages that were dropped from the start of the console log because it was full.
+func (b *Browser) GetDroppedConsoleMessageCount() int {
+	b.console.mu.Lock()
+	defer b.console.mu.Unlock()
+	return b.console.dropped
+}
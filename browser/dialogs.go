@@ -0,0 +1,204 @@
+package browser
+
+import (
+	"collaborativebrowser/trajectory"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// How JavaScript dialogs are handled when they open.
+type DialogPolicy string
+
+const (
+	// Dialogs stay open until the agent accepts or dismisses them.
+	DialogPolicyNone DialogPolicy = "none"
+	// Dialogs are accepted with their default prompt text, for unattended runs.
+	DialogPolicyAccept DialogPolicy = "accept"
+	// Dialogs are dismissed, for unattended runs.
+	DialogPolicyDismiss DialogPolicy = "dismiss"
+)
+
+// Returned when the page of the active tab is blocked by an open JavaScript dialog.
+var ErrDialogOpen = errors.New("a javascript dialog is open")
+
+type Dialog struct {
+	Type          page.DialogType
+	Message       string
+	DefaultPrompt string
+	URL           string
+}
+
+func (d *Dialog) String() string {
+	return fmt.Sprintf("%s dialog \"%s\"", d.Type, d.Message)
+}
+
+// Tracks the JavaScript dialog of a tab. A page cannot run scripts or be inspected while a dialog is open, so
+// commands that are waiting on the page are canceled when a dialog opens.
+type dialogTracker struct {
+	mu     sync.Mutex
+	open   *Dialog
+	cancel func()
+	// descriptions of the dialogs that were handled by the dialog policy and have not been reported yet
+	handled []string
+}
+
+func (t *dialogTracker) current() *Dialog {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.open
+}
+
+func (t *dialogTracker) setOpen(dialog *Dialog) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.open = dialog
+	if dialog != nil && t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// Registers a function that is called when a dialog opens, until the returned function is called.
+func (t *dialogTracker) onOpen(cancel func()) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancel = cancel
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.cancel = nil
+	}
+}
+
+func (t *dialogTracker) recordHandled(description string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handled = append(t.handled, description)
+}
+
+func (t *dialogTracker) takeHandled() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	handled := t.handled
+	t.handled = nil
+	return handled
+}
+
+func (b *Browser) handleDialogEvent(t *tab, ev interface{}) {
+	switch ev := ev.(type) {
+	case *page.EventJavascriptDialogOpening:
+		dialog := &Dialog{
+			Type:          ev.Type,
+			Message:       ev.Message,
+			DefaultPrompt: ev.DefaultPrompt,
+			URL:           ev.URL,
+		}
+		if b.dialogPolicy != DialogPolicyAccept && b.dialogPolicy != DialogPolicyDismiss {
+			t.dialogs.setOpen(dialog)
+			return
+		}
+		accept := b.dialogPolicy == DialogPolicyAccept
+		verb := "dismissed"
+		if accept {
+			verb = "accepted"
+		}
+		t.dialogs.recordHandled(fmt.Sprintf("%s was %s automatically", dialog, verb))
+		// event handlers must not block, so the dialog is handled in a separate goroutine
+		go func() {
+			if err := chromedp.Run(t.ctx, page.HandleJavaScriptDialog(accept).WithPromptText(dialog.DefaultPrompt)); err != nil {
+				log.Printf("error handling %s: %v", dialog, err)
+			}
+		}()
+	case *page.EventJavascriptDialogClosed:
+		t.dialogs.setOpen(nil)
+	}
+}
+
+func (b *Browser) SetDialogPolicy(policy DialogPolicy) error {
+	if policy != DialogPolicyNone && policy != DialogPolicyAccept && policy != DialogPolicyDismiss {
+		return fmt.Errorf("unsupported dialog policy: %s", policy)
+	}
+	b.dialogPolicy = policy
+	return nil
+}
+
+// Returns the dialog that is open in the active tab, or nil if there is none.
+func (b *Browser) GetOpenDialog() *Dialog {
+	if b.activeTab == nil {
+		return nil
+	}
+	return b.activeTab.dialogs.current()
+}
+
+// Accepts the open dialog of the active tab. The prompt text is only used by prompt dialogs.
+func (b *Browser) AcceptDialog(promptText string) error {
+	return b.closeDialog(true, promptText, trajectory.BrowserActionTypeAcceptDialog)
+}
+
+func (b *Browser) DismissDialog() error {
+	return b.closeDialog(false, "", trajectory.BrowserActionTypeDismissDialog)
+}
+
+func (b *Browser) closeDialog(accept bool, promptText string, actionType trajectory.BrowserActionType) error {
+	t := b.activeTab
+	dialog := t.dialogs.current()
+	if dialog == nil {
+		return errors.New("no dialog is open")
+	}
+	params := page.HandleJavaScriptDialog(accept)
+	if accept && dialog.Type == page.DialogTypePrompt {
+		if promptText == "" {
+			promptText = dialog.DefaultPrompt
+		}
+		params = params.WithPromptText(promptText)
+	}
+	// the page is blocked by the dialog, so the command is sent directly instead of through b.run
+	if err := chromedp.Run(t.ctx, params); err != nil {
+		return fmt.Errorf("error closing %s: %w", dialog, err)
+	}
+	t.dialogs.setOpen(nil)
+	b.waitForPageToSettle(actionType)
+	return b.updateDisplay()
+}
+
+// Returns the dialogs that were handled by the dialog policy in any tab since the last call.
+func (b *Browser) takeHandledDialogs() []string {
+	handled := []string{}
+	for _, t := range b.tabs {
+		handled = append(handled, t.dialogs.takeHandled()...)
+	}
+	return handled
+}
+
+func isDialogActionType(actionType trajectory.BrowserActionType) bool {
+	return actionType == trajectory.BrowserActionTypeAcceptDialog || actionType == trajectory.BrowserActionTypeDismissDialog
+}
+
+func renderDialog(dialog *Dialog) string {
+	lines := []string{
+		"----- DIALOG -----",
+		fmt.Sprintf("%s: %s", dialog.Type, dialog.Message),
+	}
+	if dialog.Type == page.DialogTypePrompt {
+		lines = append(lines, fmt.Sprintf("default prompt text: \"%s\"", dialog.DefaultPrompt))
+	}
+	lines = append(lines, "The page is blocked until the dialog is accepted or dismissed.", "----- END DIALOG -----")
+	return strings.Join(lines, "\n")
+}
+
+// Renders the display of the active tab: the tabs header, the open dialog if there is one, and the page content.
+func renderDisplay(tabs []*TabDisplay, emulationProfile *EmulationProfile, dialog *Dialog, content string) string {
+	display := renderTabsHeader(tabs) + "\n\n"
+	if emulationProfile != nil {
+		display += renderEmulation(emulationProfile) + "\n\n"
+	}
+	if dialog != nil {
+		display += renderDialog(dialog) + "\n\n"
+	}
+	return display + content
+}
@@ -0,0 +1,216 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+type Download struct {
+	URL      string
+	FileName string
+	// The path of the downloaded file in the artifacts directory.
+	Path string
+	Size int64
+	// The MIME type of the response that was downloaded, or else a guess from the file name or content if the
+	// response was not recorded.
+	MIMEType string
+
+	// the directory that the browser saves the download to, which is recorded when it begins since the artifacts
+	// directory may change while it is in progress
+	dir string
+}
+
+// Tracks the downloads of a browser through CDP browser events. Chrome saves each download under its guid, and
+// the tracker renames the file to its suggested name once the download has completed.
+type downloadTracker struct {
+	mu         sync.Mutex
+	dir        string
+	inProgress map[string]*Download
+	completed  []*Download
+}
+
+func newDownloadTracker(dir string) *downloadTracker {
+	return &downloadTracker{
+		dir:        dir,
+		inProgress: make(map[string]*Download),
+	}
+}
+
+func (t *downloadTracker) getDir() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dir
+}
+
+func (t *downloadTracker) setDir(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dir = dir
+}
+
+// Returns an action that makes the browser save downloads to the directory of the tracker and report their
+// progress. The directory is created once the first download begins, so that sessions without downloads leave
+// nothing behind.
+func (t *downloadTracker) configure() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		dir := t.getDir()
+		c := chromedp.FromContext(ctx)
+		return cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorAllowAndName).
+			WithDownloadPath(dir).
+			WithEventsEnabled(true).
+			Do(cdp.WithExecutor(ctx, c.Browser))
+	})
+}
+
+// Returns an action that restores the default download behavior of the browser, for browsers that are not owned
+// by this process.
+func (t *downloadTracker) restore() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		c := chromedp.FromContext(ctx)
+		return cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorDefault).Do(cdp.WithExecutor(ctx, c.Browser))
+	})
+}
+
+func (t *downloadTracker) handleEvent(ev interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch ev := ev.(type) {
+	case *cdpbrowser.EventDownloadWillBegin:
+		if err := os.MkdirAll(t.dir, 0755); err != nil {
+			log.Printf("error creating download directory %s: %v", t.dir, err)
+		}
+		t.inProgress[ev.GUID] = &Download{
+			URL:      ev.URL,
+			FileName: ev.SuggestedFilename,
+			dir:      t.dir,
+		}
+	case *cdpbrowser.EventDownloadProgress:
+		download, ok := t.inProgress[ev.GUID]
+		if !ok {
+			return
+		}
+		switch ev.State {
+		case cdpbrowser.DownloadProgressStateCompleted:
+			delete(t.inProgress, ev.GUID)
+			if err := t.finish(ev.GUID, download); err != nil {
+				log.Printf("error saving download %s: %v", download.FileName, err)
+				return
+			}
+			t.completed = append(t.completed, download)
+		case cdpbrowser.DownloadProgressStateCanceled:
+			delete(t.inProgress, ev.GUID)
+			log.Printf("download of %s was canceled", download.URL)
+		}
+	}
+}
+
+// Moves a completed download from its guid to a unique file name in its directory and fills in its size.
+func (t *downloadTracker) finish(guid string, download *Download) error {
+	name := filepath.Base(download.FileName)
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = guid
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	path := filepath.Join(download.dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(download.dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+	if err := os.Rename(filepath.Join(download.dir, guid), path); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	download.FileName = filepath.Base(path)
+	download.Path = path
+	download.Size = info.Size()
+	return nil
+}
+
+func (t *downloadTracker) hasDownloadsInProgress() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.inProgress) > 0
+}
+
+func (t *downloadTracker) takeCompleted() []*Download {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	completed := t.completed
+	t.completed = nil
+	return completed
+}
+
+func detectMIMEType(path string) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		return mimeType
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	return http.DetectContentType(head[:n])
+}
+
+// Sets the directory that downloads and other artifacts of the session are saved to.
+func (b *Browser) SetArtifactsDirectory(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("error resolving artifacts directory %s: %w", dir, err)
+	}
+	b.downloads.setDir(abs)
+	return chromedp.Run(b.browserCtx, b.downloads.configure())
+}
+
+func (b *Browser) GetArtifactsDirectory() string {
+	return b.downloads.getDir()
+}
+
+// Returns the downloads that have completed since the last call.
+func (b *Browser) TakeCompletedDownloads() []*Download {
+	completed := b.downloads.takeCompleted()
+	for _, download := range completed {
+		if download.MIMEType = b.networkLog.responseMIMEType(download.URL); download.MIMEType == "" {
+			download.MIMEType = detectMIMEType(download.Path)
+		}
+	}
+	return completed
+}
+
+// Waits until downloads that were started by an action have completed, so that they can be reported with it.
+func (b *Browser) waitForDownloads(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for b.downloads.hasDownloadsInProgress() {
+		if time.Now().After(deadline) {
+			log.Println("timed out waiting for downloads to complete")
+			return
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// Returns a unique directory in the temp directory for the artifacts of a browser whose artifacts directory is not
+// set. It is not created until the first download begins.
+func defaultArtifactsDirectory() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("collaborativebrowser-artifacts-%d-%d", os.Getpid(), time.Now().UnixNano()))
+}
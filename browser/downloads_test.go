@@ -0,0 +1,59 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestNetworkRecorderResponseMIMEType(t *testing.T) {
+	recorder := newNetworkRecorder()
+	tab := &tab{id: "tab-0"}
+	respond := func(requestID network.RequestID, url string, mimeType string) {
+		recorder.handleEvent(tab, &network.EventRequestWillBeSent{RequestID: requestID, Request: &network.Request{URL: url}})
+		recorder.handleEvent(tab, &network.EventResponseReceived{RequestID: requestID, Response: &network.Response{URL: url, MimeType: mimeType}})
+	}
+	respond("1", "https://example.com/export", "text/html")
+	respond("2", "https://example.com/export", "text/csv")
+	respond("3", "https://example.com/report#page=2", "application/pdf")
+	recorder.handleEvent(tab, &network.EventRequestWillBeSent{RequestID: "4", Request: &network.Request{URL: "https://example.com/pending"}})
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/export", "text/csv"},
+		{"https://example.com/report", "application/pdf"},
+		{"https://example.com/report#page=1", "application/pdf"},
+		{"https://example.com/pending", ""},
+		{"https://example.com/other", ""},
+	}
+	for _, test := range tests {
+		if got := recorder.responseMIMEType(test.url); got != test.want {
+			t.Errorf("responseMIMEType(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
+
+func TestDetectMIMEType(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"data.json", "{}", "application/json"},
+		{"page", "<!DOCTYPE html><html></html>", "text/html; charset=utf-8"},
+		{"export", "name,price\napple,1\n", "text/plain; charset=utf-8"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dir, test.name)
+		if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := detectMIMEType(path); got != test.want {
+			t.Errorf("detectMIMEType(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
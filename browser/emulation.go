@@ -0,0 +1,275 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+)
+
+// Describes the device and region that the browser emulates. Fields that are not set are not emulated.
+type EmulationProfile struct {
+	Name string `json:"name"`
+	// The size of the viewport in CSS pixels. The device metrics are only emulated if both are set.
+	Width             int64   `json:"width,omitempty"`
+	Height            int64   `json:"height,omitempty"`
+	DeviceScaleFactor float64 `json:"deviceScaleFactor,omitempty"`
+	// Whether pages are laid out as on a mobile device, with a meta viewport and overlay scrollbars.
+	Mobile    bool   `json:"mobile,omitempty"`
+	Touch     bool   `json:"touch,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+	// The platform that navigator.platform reports, such as "iPhone".
+	Platform string `json:"platform,omitempty"`
+	// The Accept-Language header and navigator.languages, such as "de-DE,de;q=0.9". Its first language is also
+	// used as the locale of the page.
+	AcceptLanguage string       `json:"acceptLanguage,omitempty"`
+	Geolocation    *Geolocation `json:"geolocation,omitempty"`
+	// The time zone of the page, such as "Europe/Berlin".
+	Timezone string `json:"timezone,omitempty"`
+}
+
+type Geolocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// The accuracy in meters; defaults to 100.
+	Accuracy float64 `json:"accuracy,omitempty"`
+}
+
+const defaultGeolocationAccuracy = 100
+
+// Built-in profiles of common devices. Profiles for regions can be loaded with LoadEmulationProfile.
+var emulationProfiles = map[string]*EmulationProfile{
+	"iphone-14": {
+		Name:              "iphone-14",
+		Width:             390,
+		Height:            844,
+		DeviceScaleFactor: 3,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+		Platform:          "iPhone",
+	},
+	"pixel-7": {
+		Name:              "pixel-7",
+		Width:             412,
+		Height:            915,
+		DeviceScaleFactor: 2.625,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36",
+		Platform:          "Linux armv8l",
+	},
+	"ipad": {
+		Name:              "ipad",
+		Width:             820,
+		Height:            1180,
+		DeviceScaleFactor: 2,
+		Mobile:            true,
+		Touch:             true,
+		UserAgent:         "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+		Platform:          "iPad",
+	},
+	"desktop-1080p": {
+		Name:              "desktop-1080p",
+		Width:             1920,
+		Height:            1080,
+		DeviceScaleFactor: 1,
+	},
+}
+
+func EmulationProfileNames() []string {
+	names := make([]string, 0, len(emulationProfiles))
+	for name := range emulationProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns a copy of the built-in profile with the name.
+func EmulationProfileByName(name string) (*EmulationProfile, error) {
+	profile, ok := emulationProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown emulation profile %s; one of [%s]", name, strings.Join(EmulationProfileNames(), ", "))
+	}
+	clone := *profile
+	return &clone, nil
+}
+
+// Reads a profile from a JSON file. The name of the profile defaults to the path of the file.
+func LoadEmulationProfile(path string) (*EmulationProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading emulation profile %s: %w", path, err)
+	}
+	profile := &EmulationProfile{}
+	if err := json.Unmarshal(data, profile); err != nil {
+		return nil, fmt.Errorf("error parsing emulation profile %s: %w", path, err)
+	}
+	if profile.Name == "" {
+		profile.Name = path
+	}
+	return profile, nil
+}
+
+func (p *EmulationProfile) hasDeviceMetrics() bool {
+	return p.Width > 0 && p.Height > 0
+}
+
+// Returns a one line summary of the profile, such as "iphone-14 (390x844, mobile, touch, timezone Europe/Berlin)".
+func (p *EmulationProfile) String() string {
+	details := []string{}
+	if p.hasDeviceMetrics() {
+		details = append(details, fmt.Sprintf("%dx%d", p.Width, p.Height))
+	}
+	if p.Mobile {
+		details = append(details, "mobile")
+	}
+	if p.Touch {
+		details = append(details, "touch")
+	}
+	if p.AcceptLanguage != "" {
+		details = append(details, "language "+p.AcceptLanguage)
+	}
+	if p.Timezone != "" {
+		details = append(details, "timezone "+p.Timezone)
+	}
+	if p.Geolocation != nil {
+		details = append(details, fmt.Sprintf("location %.4f,%.4f", p.Geolocation.Latitude, p.Geolocation.Longitude))
+	}
+	if len(details) == 0 {
+		return p.Name
+	}
+	return fmt.Sprintf("%s (%s)", p.Name, strings.Join(details, ", "))
+}
+
+// Returns the first language of the Accept-Language header of the profile, such as "de-DE".
+func (p *EmulationProfile) locale() string {
+	first, _, _ := strings.Cut(p.AcceptLanguage, ",")
+	first, _, _ = strings.Cut(first, ";")
+	return strings.TrimSpace(first)
+}
+
+// Returns the actions that emulate the profile in a tab, or that reset the emulation if the profile is nil. The
+// overrides are reset first, since Chrome rejects a time zone or locale override while another one is in effect.
+func emulate(profile *EmulationProfile) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		resets := []chromedp.Action{
+			emulation.ClearDeviceMetricsOverride(),
+			emulation.SetTouchEmulationEnabled(false),
+			emulation.SetUserAgentOverride(""),
+			emulation.ClearGeolocationOverride(),
+			emulation.SetTimezoneOverride(""),
+			emulation.SetLocaleOverride(),
+		}
+		for _, action := range resets {
+			if err := action.Do(ctx); err != nil {
+				return fmt.Errorf("error resetting emulation: %w", err)
+			}
+		}
+		if profile == nil {
+			return nil
+		}
+		actions := []chromedp.Action{}
+		if profile.hasDeviceMetrics() {
+			scale := profile.DeviceScaleFactor
+			if scale == 0 {
+				scale = 1
+			}
+			actions = append(actions, emulation.SetDeviceMetricsOverride(profile.Width, profile.Height, scale, profile.Mobile))
+		}
+		if profile.Touch {
+			actions = append(actions, emulation.SetTouchEmulationEnabled(true).WithMaxTouchPoints(5))
+		}
+		if profile.UserAgent != "" || profile.AcceptLanguage != "" || profile.Platform != "" {
+			userAgent := profile.UserAgent
+			if userAgent == "" {
+				// an empty user agent keeps the user agent of the browser
+				c := chromedp.FromContext(ctx)
+				if _, _, _, ua, _, err := cdpbrowser.GetVersion().Do(cdp.WithExecutor(ctx, c.Browser)); err == nil {
+					userAgent = ua
+				}
+			}
+			override := emulation.SetUserAgentOverride(userAgent)
+			if profile.AcceptLanguage != "" {
+				override = override.WithAcceptLanguage(profile.AcceptLanguage)
+			}
+			if profile.Platform != "" {
+				override = override.WithPlatform(profile.Platform)
+			}
+			actions = append(actions, override)
+		}
+		if locale := profile.locale(); locale != "" {
+			actions = append(actions, emulation.SetLocaleOverride().WithLocale(locale))
+		}
+		if profile.Timezone != "" {
+			actions = append(actions, emulation.SetTimezoneOverride(profile.Timezone))
+		}
+		if geolocation := profile.Geolocation; geolocation != nil {
+			accuracy := geolocation.Accuracy
+			if accuracy == 0 {
+				accuracy = defaultGeolocationAccuracy
+			}
+			c := chromedp.FromContext(ctx)
+			permissions := []cdpbrowser.PermissionType{cdpbrowser.PermissionTypeGeolocation}
+			if err := cdpbrowser.GrantPermissions(permissions).Do(cdp.WithExecutor(ctx, c.Browser)); err != nil {
+				log.Println("error granting geolocation permission:", err)
+			}
+			actions = append(actions, emulation.SetGeolocationOverride().
+				WithLatitude(geolocation.Latitude).
+				WithLongitude(geolocation.Longitude).
+				WithAccuracy(accuracy))
+		}
+		for _, action := range actions {
+			if err := action.Do(ctx); err != nil {
+				return fmt.Errorf("error emulating %s: %w", profile.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Emulates the profile in all attached tabs and in the tabs that are attached later, or stops emulating if the profile is
+// nil. The page is reloaded so that it is laid out and served for the profile.
+func (b *Browser) SetEmulationProfile(profile *EmulationProfile) error {
+	for _, t := range b.tabs {
+		if !t.isAttached() {
+			continue
+		} else if err := chromedp.Run(t.ctx, emulate(profile)); err != nil {
+			return fmt.Errorf("error setting emulation profile for tab %s: %w", t.id, err)
+		}
+	}
+	b.emulationProfile = profile
+	if location, err := b.getLocation(); err == nil && !isInternalPage(location) {
+		if err := b.Reload(); err != nil {
+			return err
+		}
+	}
+	return b.updateDisplay()
+}
+
+func (b *Browser) GetEmulationProfile() *EmulationProfile {
+	return b.emulationProfile
+}
+
+// Applies the emulation profile of the browser to a newly opened tab.
+func (b *Browser) applyEmulationProfile(t *tab) {
+	if b.emulationProfile == nil {
+		return
+	}
+	if err := chromedp.Run(t.ctx, emulate(b.emulationProfile)); err != nil {
+		log.Printf("error applying emulation profile to tab %s: %v", t.id, err)
+	}
+}
+
+func renderEmulation(profile *EmulationProfile) string {
+	return fmt.Sprintf("----- EMULATION -----\n%s\n----- END EMULATION -----", profile)
+}
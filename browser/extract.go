@@ -0,0 +1,716 @@
+package browser
+
+import (
+	"collaborativebrowser/trajectory"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Rows that were extracted from a table, a list, or repeated cards of a page.
+type Extraction struct {
+	// Where the rows were found, such as `table "Prices"` or `12 cards (div.product)`.
+	Source   string
+	Location string
+	Fields   []string
+	// The values of each row in the order of the fields; values that were not found are empty.
+	Rows [][]string
+}
+
+func (e *Extraction) String() string {
+	return fmt.Sprintf("%d rows with fields [%s] from %s", len(e.Rows), strings.Join(e.Fields, ", "), e.Source)
+}
+
+const (
+	// The most rows that are extracted from a dataset.
+	maxExtractionRows = 1000
+	// The token budget of the rows in the observation of an extract action; all rows are kept in the trajectory.
+	extractionObservationMaxTokens = 2000
+	// The fewest repeated elements that are considered a list, and the fewest that are considered cards.
+	minListItems = 2
+	minCards     = 3
+)
+
+// A table, list, or set of repeated cards on the page, with its rows and the names of its columns.
+type dataset struct {
+	source  string
+	columns []string
+	rows    [][]string
+	// text that describes the dataset, such as the caption of a table, which a schema is matched against
+	label string
+}
+
+// Extracts rows from the table, list, or repeated cards of the active page that best match the fields or the
+// schema. With explicit fields, each row has a value for every field, which is empty if the dataset has no column
+// that matches the field. A natural language schema, such as "the name, price, and rating of each product", picks
+// the dataset and the columns that match its words. If neither is given, all columns of the largest dataset are
+// extracted. Returns nil if the page has no tables, lists, or repeated cards. The html of the page is read again,
+// so that rows that were loaded after the page was last rendered are included.
+func (b *Browser) Extract(fields []string, schema string) (*Extraction, error) {
+	location, err := b.getLocation()
+	if err != nil {
+		return nil, fmt.Errorf("error getting location: %w", err)
+	}
+	pageHTML, err := b.getHTML()
+	if err != nil {
+		return nil, fmt.Errorf("error getting html for location %s: %w", location, err)
+	}
+	root, err := html.Parse(strings.NewReader(pageHTML))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing html: %w", err)
+	}
+	baseURL, _ := url.Parse(location)
+	datasets := collectDatasets(root, baseURL)
+	if len(datasets) == 0 {
+		return nil, nil
+	}
+	explicit := len(fields) > 0
+	if !explicit {
+		fields = parseSchemaFields(schema)
+	}
+	schemaTokens := tokenizeFieldName(schema)
+	var best *dataset
+	var bestColumns []int
+	bestScore := -1
+	for _, ds := range datasets {
+		columns := matchColumns(ds, fields)
+		score := scoreDataset(ds, columns, schemaTokens)
+		if score > bestScore {
+			best, bestColumns, bestScore = ds, columns, score
+		}
+	}
+	extraction := &Extraction{
+		Source:   best.source,
+		Location: location,
+	}
+	if !explicit {
+		// the fields of a schema are only kept if they match a column, and all columns are kept if none match
+		fields, bestColumns = matchedFields(fields, bestColumns)
+		if len(fields) == 0 {
+			fields = best.columns
+			bestColumns = make([]int, len(best.columns))
+			for i := range bestColumns {
+				bestColumns[i] = i
+			}
+		}
+	}
+	extraction.Fields = fields
+	for _, row := range best.rows {
+		values := make([]string, len(fields))
+		for i, column := range bestColumns {
+			if column >= 0 && column < len(row) {
+				values[i] = row[column]
+			}
+		}
+		extraction.Rows = append(extraction.Rows, values)
+	}
+	return extraction, nil
+}
+
+// Returns the extraction of the last extract action, or nil if the last action was not an extraction.
+func (b *Browser) GetLastExtraction() *Extraction {
+	return b.lastExtraction
+}
+
+// Extracts rows for an extract action and describes them for its observation, trimmed to a token budget.
+func (b *Browser) extractForAction(fields []string, schema string) (string, error) {
+	extraction, err := b.Extract(fields, schema)
+	if err != nil {
+		return "", err
+	} else if extraction == nil {
+		return "found no tables, lists, or repeated elements to extract rows from", nil
+	}
+	b.lastExtraction = extraction
+	data, err := trajectory.FormatRowsAsJSON(extraction.Fields, extraction.Rows)
+	if err != nil {
+		return "", fmt.Errorf("error encoding rows: %w", err)
+	}
+	return fmt.Sprintf("extracted %s\n%s", extraction, trimJSON(string(data), extractionObservationMaxTokens)), nil
+}
+
+func collectDatasets(root *html.Node, baseURL *url.URL) []*dataset {
+	datasets := []*dataset{}
+	tableCount := 0
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && isSkippedForExtraction(n) {
+			return
+		}
+		if n.Type == html.ElementNode && n.Data == "table" {
+			tableCount++
+			if ds := newTableDataset(n, tableCount, baseURL); ds != nil {
+				datasets = append(datasets, ds)
+			}
+			// the cells of a table are only extracted as part of the table
+			return
+		}
+		if n.Type == html.ElementNode {
+			if ds := newCardsDataset(n, baseURL); ds != nil {
+				datasets = append(datasets, ds)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(root)
+	return datasets
+}
+
+var skippedExtractionElements = map[string]bool{
+	"head":     true,
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"select":   true,
+	"svg":      true,
+}
+
+func isSkippedForExtraction(n *html.Node) bool {
+	if skippedExtractionElements[n.Data] {
+		return true
+	}
+	if getAttr(n, "aria-hidden") == "true" || hasAttr(n, "hidden") {
+		return true
+	}
+	style := getAttr(n, "style")
+	return strings.Contains(style, "display: none") || strings.Contains(style, "visibility: hidden")
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+var whitespaceRegexp = regexp.MustCompile(`\s+`)
+
+// Returns the visible text of a node with its whitespace collapsed.
+func extractText(n *html.Node) string {
+	var sb strings.Builder
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		case html.ElementNode:
+			if isSkippedForExtraction(n) {
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+	return strings.TrimSpace(whitespaceRegexp.ReplaceAllString(sb.String(), " "))
+}
+
+func resolveURL(baseURL *url.URL, ref string) string {
+	if baseURL == nil || ref == "" {
+		return ref
+	}
+	resolved, err := baseURL.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return resolved.String()
+}
+
+// Returns the elements below n with one of the tags, without descending into nested tables.
+func findInTable(n *html.Node, tags ...string) []*html.Node {
+	found := []*html.Node{}
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || isSkippedForExtraction(c) {
+				continue
+			}
+			for _, tag := range tags {
+				if c.Data == tag {
+					found = append(found, c)
+					break
+				}
+			}
+			if c.Data != "table" {
+				visit(c)
+			}
+		}
+	}
+	visit(n)
+	return found
+}
+
+func newTableDataset(table *html.Node, index int, baseURL *url.URL) *dataset {
+	rows := findInTable(table, "tr")
+	if len(rows) == 0 {
+		return nil
+	}
+	var header []string
+	isHeaderRow := func(row *html.Node) bool {
+		if row.Parent != nil && row.Parent.Data == "thead" {
+			return true
+		}
+		cells := findInTable(row, "td", "th")
+		for _, cell := range cells {
+			if cell.Data != "th" {
+				return false
+			}
+		}
+		return len(cells) > 0
+	}
+	if isHeaderRow(rows[0]) {
+		for _, cell := range findInTable(rows[0], "td", "th") {
+			for i := 0; i < colspan(cell); i++ {
+				header = append(header, extractText(cell))
+			}
+		}
+		rows = rows[1:]
+	}
+	values := [][]string{}
+	links := [][]string{}
+	width := len(header)
+	for _, row := range rows {
+		if isHeaderRow(row) {
+			continue
+		}
+		rowValues, rowLinks := []string{}, []string{}
+		for _, cell := range findInTable(row, "td", "th") {
+			rowValues = append(rowValues, extractText(cell))
+			rowLinks = append(rowLinks, cellLink(cell, baseURL))
+			for i := 1; i < colspan(cell); i++ {
+				rowValues = append(rowValues, "")
+				rowLinks = append(rowLinks, "")
+			}
+		}
+		if strings.Join(rowValues, "") == "" {
+			continue
+		}
+		values = append(values, rowValues)
+		links = append(links, rowLinks)
+		width = max(width, len(rowValues))
+		if len(values) == maxExtractionRows {
+			break
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	columns := make([]string, width)
+	for i := range columns {
+		if i < len(header) && header[i] != "" {
+			columns[i] = header[i]
+		} else {
+			columns[i] = fmt.Sprintf("column_%d", i+1)
+		}
+	}
+	// the links of a column are added as another column, since they often lead to the details of a row
+	for i := 0; i < width; i++ {
+		hasLinks := false
+		for _, rowLinks := range links {
+			if i < len(rowLinks) && rowLinks[i] != "" {
+				hasLinks = true
+				break
+			}
+		}
+		if !hasLinks {
+			continue
+		}
+		columns = append(columns, columns[i]+" link")
+		for j, rowLinks := range links {
+			link := ""
+			if i < len(rowLinks) {
+				link = rowLinks[i]
+			}
+			values[j] = append(padRow(values[j], width), link)
+		}
+		width++
+	}
+	ds := &dataset{
+		columns: uniqueNames(columns),
+		label:   tableLabel(table),
+	}
+	for _, row := range values {
+		ds.rows = append(ds.rows, padRow(row, len(columns)))
+	}
+	if ds.label != "" {
+		ds.source = fmt.Sprintf("table \"%s\"", ds.label)
+	} else {
+		ds.source = fmt.Sprintf("table %d", index)
+	}
+	return ds
+}
+
+func colspan(cell *html.Node) int {
+	var span int
+	if _, err := fmt.Sscanf(getAttr(cell, "colspan"), "%d", &span); err != nil || span < 1 {
+		return 1
+	}
+	return min(span, 100)
+}
+
+func cellLink(cell *html.Node, baseURL *url.URL) string {
+	for _, a := range findInTable(cell, "a") {
+		if href := getAttr(a, "href"); href != "" && !strings.HasPrefix(href, "javascript:") {
+			return resolveURL(baseURL, href)
+		}
+	}
+	return ""
+}
+
+func padRow(row []string, width int) []string {
+	for len(row) < width {
+		row = append(row, "")
+	}
+	return row
+}
+
+func tableLabel(table *html.Node) string {
+	for _, caption := range findInTable(table, "caption") {
+		if text := extractText(caption); text != "" {
+			return text
+		}
+	}
+	if label := getAttr(table, "aria-label"); label != "" {
+		return label
+	}
+	return precedingHeading(table)
+}
+
+// Returns the text of the heading right before an element, which often names the table or list below it.
+func precedingHeading(n *html.Node) string {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if isHeading(s) {
+			return extractText(s)
+		}
+		return ""
+	}
+	return ""
+}
+
+func isHeading(n *html.Node) bool {
+	return len(n.Data) == 2 && n.Data[0] == 'h' && n.Data[1] >= '1' && n.Data[1] <= '6'
+}
+
+// Returns the signature of an element that repeated cards share, which is its tag and its classes.
+func cardSignature(n *html.Node) string {
+	classes := strings.Fields(getAttr(n, "class"))
+	sort.Strings(classes)
+	return strings.Join(append([]string{n.Data}, classes...), ".")
+}
+
+func newCardsDataset(container *html.Node, baseURL *url.URL) *dataset {
+	groups := make(map[string][]*html.Node)
+	order := []string{}
+	for c := container.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || isSkippedForExtraction(c) || extractText(c) == "" {
+			continue
+		}
+		signature := cardSignature(c)
+		if _, ok := groups[signature]; !ok {
+			order = append(order, signature)
+		}
+		groups[signature] = append(groups[signature], c)
+	}
+	var signature string
+	for _, s := range order {
+		if len(groups[s]) > len(groups[signature]) {
+			signature = s
+		}
+	}
+	cards := groups[signature]
+	isList := len(cards) > 0 && (cards[0].Data == "li" || cards[0].Data == "dd")
+	if (isList && len(cards) < minListItems) || (!isList && len(cards) < minCards) {
+		return nil
+	}
+	if len(cards) > maxExtractionRows {
+		cards = cards[:maxExtractionRows]
+	}
+	cardFields := make([][]*cardField, len(cards))
+	counts := make(map[string]int)
+	columns := []string{}
+	for i, card := range cards {
+		cardFields[i] = collectCardFields(card, baseURL)
+		for _, field := range cardFields[i] {
+			if counts[field.name] == 0 {
+				columns = append(columns, field.name)
+			}
+			counts[field.name]++
+		}
+	}
+	// fields that only some cards have, such as a badge, are left out so that the rows line up
+	kept := []string{}
+	for _, column := range columns {
+		if counts[column]*2 >= len(cards) {
+			kept = append(kept, column)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	ds := &dataset{columns: kept}
+	for _, fields := range cardFields {
+		row := make([]string, len(kept))
+		for i, column := range kept {
+			for _, field := range fields {
+				if field.name == column {
+					row[i] = field.value
+					break
+				}
+			}
+		}
+		ds.rows = append(ds.rows, row)
+	}
+	ds.label = getAttr(container, "aria-label")
+	if ds.label == "" {
+		ds.label = precedingHeading(container)
+	}
+	kind := "cards"
+	if isList {
+		kind = "list items"
+	}
+	ds.source = fmt.Sprintf("%d %s (%s)", len(cards), kind, signature)
+	if ds.label != "" {
+		ds.source = fmt.Sprintf("%s under \"%s\"", ds.source, ds.label)
+	}
+	return ds
+}
+
+type cardField struct {
+	name  string
+	value string
+}
+
+// Collects the fields of a card in document order: the texts of its elements, named by their itemprop, their
+// classes, or their role, and the urls of its links and images.
+func collectCardFields(card *html.Node, baseURL *url.URL) []*cardField {
+	fields := []*cardField{}
+	seen := make(map[string]int)
+	add := func(name string, value string) {
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, seen[name])
+		}
+		fields = append(fields, &cardField{name: name, value: value})
+	}
+	var visit func(n *html.Node)
+	visit = func(n *html.Node) {
+		switch {
+		case n.Type == html.ElementNode && isSkippedForExtraction(n):
+			return
+		case n.Type == html.ElementNode && n.Data == "a" && getAttr(n, "href") != "" && !strings.HasPrefix(getAttr(n, "href"), "javascript:"):
+			add("link", resolveURL(baseURL, getAttr(n, "href")))
+		case n.Type == html.ElementNode && n.Data == "img" && getAttr(n, "src") != "":
+			add("image", resolveURL(baseURL, getAttr(n, "src")))
+		case n.Type == html.ElementNode && isTextLeaf(n):
+			if text := extractText(n); text != "" {
+				add(cardFieldName(n, card), text)
+			}
+			return
+		case n.Type == html.TextNode:
+			if text := strings.TrimSpace(whitespaceRegexp.ReplaceAllString(n.Data, " ")); text != "" {
+				add(cardFieldName(n.Parent, card), text)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	for c := card.FirstChild; c != nil; c = c.NextSibling {
+		visit(c)
+	}
+	return fields
+}
+
+// Whether an element only contains text and inline formatting, so that its text is a single value.
+func isTextLeaf(n *html.Node) bool {
+	hasText := false
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch {
+		case c.Type == html.TextNode:
+			if strings.TrimSpace(c.Data) != "" {
+				hasText = true
+			}
+		case c.Type == html.ElementNode && c.Data == "br":
+		case c.Type == html.ElementNode && inlineFormattingElements[c.Data] && isTextLeaf(c):
+			hasText = true
+		case c.Type == html.ElementNode:
+			return false
+		}
+	}
+	return hasText
+}
+
+var inlineFormattingElements = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "small": true, "sup": true, "sub": true, "mark": true,
+}
+
+var classNameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z_-]{2,40}$`)
+
+// Returns the name of the field of a text in a card, from the element of the text or its closest ancestor in the
+// card that has an itemprop or a meaningful class, or "title" for headings and "text" otherwise.
+func cardFieldName(n *html.Node, card *html.Node) string {
+	for e := n; e != nil && e != card; e = e.Parent {
+		if e.Type != html.ElementNode {
+			continue
+		}
+		if itemprop := getAttr(e, "itemprop"); itemprop != "" {
+			return itemprop
+		}
+		if isHeading(e) {
+			return "title"
+		}
+		for _, class := range strings.Fields(getAttr(e, "class")) {
+			if classNameRegexp.MatchString(class) {
+				return strings.ToLower(strings.ReplaceAll(class, "-", "_"))
+			}
+		}
+	}
+	return "text"
+}
+
+func uniqueNames(names []string) []string {
+	seen := make(map[string]int)
+	unique := make([]string, len(names))
+	for i, name := range names {
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, seen[name])
+		}
+		unique[i] = name
+	}
+	return unique
+}
+
+// Words that mean the same in the names of fields, mapped to one of them.
+var fieldNameSynonyms = map[string]string{
+	"url":       "link",
+	"href":      "link",
+	"name":      "title",
+	"heading":   "title",
+	"img":       "image",
+	"photo":     "image",
+	"picture":   "image",
+	"thumbnail": "image",
+	"cost":      "price",
+	"amount":    "price",
+}
+
+var fieldNameStopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "of": true, "and": true, "each": true, "every": true, "all": true,
+	"for": true, "with": true, "its": true, "their": true, "in": true, "on": true, "from": true, "to": true,
+}
+
+var nonAlphanumericRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Splits the name of a field into lowercase words without stop words, in singular, and with synonyms unified.
+func tokenizeFieldName(name string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, word := range nonAlphanumericRegexp.Split(strings.ToLower(name), -1) {
+		if word == "" || fieldNameStopWords[word] {
+			continue
+		}
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = strings.TrimSuffix(word, "s")
+		}
+		if synonym, ok := fieldNameSynonyms[word]; ok {
+			word = synonym
+		}
+		tokens[word] = true
+	}
+	return tokens
+}
+
+var (
+	schemaSeparatorRegexp = regexp.MustCompile(`,|;|\n|\band\b`)
+	// the articles before a field and the phrases after it that refer to the rows, as in "the rating of each product"
+	schemaArticleRegexp   = regexp.MustCompile(`(?i)^(the|a|an|its|their)\s+`)
+	schemaRowPhraseRegexp = regexp.MustCompile(`(?i)\s+(of|for|per|in|on)\s+(each|every|all|the)\b.*$`)
+)
+
+// Splits a natural language schema such as "the name, price, and rating of each product" into the names of its
+// fields, such as "name", "price", and "rating".
+func parseSchemaFields(schema string) []string {
+	fields := []string{}
+	for _, part := range schemaSeparatorRegexp.Split(schema, -1) {
+		field := schemaRowPhraseRegexp.ReplaceAllString(strings.TrimSpace(part), "")
+		field = schemaArticleRegexp.ReplaceAllString(field, "")
+		if len(tokenizeFieldName(field)) > 0 {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Returns the index of the column that best matches each field, or -1 if no column shares a word with the field.
+func matchColumns(ds *dataset, fields []string) []int {
+	columns := make([]int, len(fields))
+	for i, field := range fields {
+		columns[i] = -1
+		fieldTokens := tokenizeFieldName(field)
+		bestOverlap := 0
+		for j, column := range ds.columns {
+			overlap := 0
+			for token := range tokenizeFieldName(column) {
+				if fieldTokens[token] {
+					overlap++
+				}
+			}
+			if overlap > bestOverlap {
+				columns[i], bestOverlap = j, overlap
+			}
+		}
+	}
+	return columns
+}
+
+func matchedFields(fields []string, columns []int) ([]string, []int) {
+	matchedFields, matchedColumns := []string{}, []int{}
+	for i, column := range columns {
+		if column >= 0 {
+			matchedFields = append(matchedFields, fields[i])
+			matchedColumns = append(matchedColumns, column)
+		}
+	}
+	return matchedFields, matchedColumns
+}
+
+// Scores how well a dataset matches the requested fields and schema. Matching fields count the most, then the
+// words of the schema that appear in the columns or the label of the dataset, and then the size of the dataset.
+func scoreDataset(ds *dataset, columns []int, schemaTokens map[string]bool) int {
+	matched := 0
+	for _, column := range columns {
+		if column >= 0 {
+			matched++
+		}
+	}
+	described := make(map[string]bool)
+	for token := range tokenizeFieldName(strings.Join(append(ds.columns, ds.label), " ")) {
+		if schemaTokens[token] {
+			described[token] = true
+		}
+	}
+	return matched*10000 + len(described)*1000 + min(len(ds.rows)*len(ds.columns), 999)
+}
@@ -0,0 +1,175 @@
+package browser
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseSchemaFields(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   []string
+	}{
+		{"", []string{}},
+		{"name", []string{"name"}},
+		{"name, price", []string{"name", "price"}},
+		{"the name, price, and rating of each product", []string{"name", "price", "rating"}},
+		{"title and author", []string{"title", "author"}},
+		{"the title of every book; its author", []string{"title", "author"}},
+		{"price per item", []string{"price per item"}},
+		{"name\nprice\n", []string{"name", "price"}},
+		{"The Title For Each Result", []string{"Title"}},
+		// parts without words other than stop words are not fields
+		{"name, and, the", []string{"name"}},
+		// "and" inside a word does not split the field
+		{"brand, band", []string{"brand", "band"}},
+	}
+	for _, test := range tests {
+		if got := parseSchemaFields(test.schema); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseSchemaFields(%q) = %q, want %q", test.schema, got, test.want)
+		}
+	}
+}
+
+func TestTokenizeFieldName(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Price", []string{"price"}},
+		{"prices", []string{"price"}},
+		{"cost", []string{"price"}},
+		{"Product Name", []string{"product", "title"}},
+		{"the url of each item", []string{"link", "item"}},
+		{"address", []string{"address"}},
+		{"ids", []string{"ids"}},
+		{"", []string{}},
+	}
+	for _, test := range tests {
+		want := make(map[string]bool)
+		for _, token := range test.want {
+			want[token] = true
+		}
+		if got := tokenizeFieldName(test.name); !reflect.DeepEqual(got, want) {
+			t.Errorf("tokenizeFieldName(%q) = %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestCollectDatasets(t *testing.T) {
+	type want struct {
+		source  string
+		columns []string
+		rows    [][]string
+	}
+	tests := []struct {
+		name string
+		html string
+		want []want
+	}{
+		{
+			"table with thead",
+			`<table><caption>Prices</caption>
+				<thead><tr><th>Name</th><th>Price</th></tr></thead>
+				<tbody><tr><td><a href="/a">Apple</a></td><td>$1</td></tr><tr><td>Pear</td><td>$2</td></tr></tbody>
+			</table>`,
+			[]want{{
+				`table "Prices"`,
+				[]string{"Name", "Price", "Name link"},
+				[][]string{{"Apple", "$1", "https://example.com/a"}, {"Pear", "$2", ""}},
+			}},
+		},
+		{
+			"table without header",
+			`<table><tr><td>a</td><td>1</td></tr><tr><td>b</td><td>2</td></tr></table>`,
+			[]want{{
+				"table 1",
+				[]string{"column_1", "column_2"},
+				[][]string{{"a", "1"}, {"b", "2"}},
+			}},
+		},
+		{
+			"repeated product cards",
+			`<h2>Products</h2>
+			<div class="grid">
+				<div class="card"><h3 class="title">A</h3><span class="price">$1</span><a href="/a">View</a></div>
+				<div class="card"><h3 class="title">B</h3><span class="price">$2</span><a href="/b">View</a></div>
+				<div class="card"><h3 class="title">C</h3><span class="price">$3</span><a href="/c">View</a></div>
+			</div>`,
+			[]want{{
+				`3 cards (div.card) under "Products"`,
+				[]string{"title", "price", "link", "text"},
+				[][]string{
+					{"A", "$1", "https://example.com/a", "View"},
+					{"B", "$2", "https://example.com/b", "View"},
+					{"C", "$3", "https://example.com/c", "View"},
+				},
+			}},
+		},
+		{
+			"nested list",
+			`<ul><li>Fruit<ul><li>Apple</li><li>Pear</li></ul></li><li>Vegetables<ul><li>Kale</li><li>Leek</li></ul></li></ul>`,
+			[]want{
+				{"2 list items (li)", []string{"text", "text_2", "text_3"}, [][]string{{"Fruit", "Apple", "Pear"}, {"Vegetables", "Kale", "Leek"}}},
+				{"2 list items (li)", []string{"text"}, [][]string{{"Apple"}, {"Pear"}}},
+				{"2 list items (li)", []string{"text"}, [][]string{{"Kale"}, {"Leek"}}},
+			},
+		},
+		{
+			"too few cards",
+			`<div><div class="card">A</div><div class="card">B</div></div>`,
+			nil,
+		},
+		{
+			"hidden table",
+			`<table style="display: none"><tr><td>a</td></tr></table>`,
+			nil,
+		},
+	}
+	baseURL, _ := url.Parse("https://example.com/shop/")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := html.Parse(strings.NewReader(test.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			datasets := collectDatasets(root, baseURL)
+			if len(datasets) != len(test.want) {
+				t.Fatalf("got %d datasets, want %d", len(datasets), len(test.want))
+			}
+			for i, ds := range datasets {
+				if ds.source != test.want[i].source {
+					t.Errorf("dataset %d: source = %q, want %q", i, ds.source, test.want[i].source)
+				}
+				if !reflect.DeepEqual(ds.columns, test.want[i].columns) {
+					t.Errorf("dataset %d: columns = %q, want %q", i, ds.columns, test.want[i].columns)
+				}
+				if !reflect.DeepEqual(ds.rows, test.want[i].rows) {
+					t.Errorf("dataset %d: rows = %q, want %q", i, ds.rows, test.want[i].rows)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchColumns(t *testing.T) {
+	ds := &dataset{columns: []string{"Product Name", "Price", "Product Name link", "image"}}
+	tests := []struct {
+		fields []string
+		want   []int
+	}{
+		{[]string{"name", "cost"}, []int{0, 1}},
+		{[]string{"url"}, []int{2}},
+		{[]string{"photo", "rating"}, []int{3, -1}},
+		{nil, []int{}},
+	}
+	for _, test := range tests {
+		if got := matchColumns(ds, test.fields); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("matchColumns(%q) = %v, want %v", test.fields, got, test.want)
+		}
+	}
+}
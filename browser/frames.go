@@ -0,0 +1,140 @@
+package browser
+
+import (
+	"collaborativebrowser/translators/html2md"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"golang.org/x/net/html"
+)
+
+// A JavaScript prelude that defines query functions that also search same-origin iframes and open shadow
+// roots. It only declares functions so that it can be evaluated repeatedly in the same page.
+const deepQueryJS = `function collectQueryRoots(root, roots) {
+	roots.push(root);
+	root.querySelectorAll('*').forEach(element => {
+		if (element.shadowRoot) {
+			collectQueryRoots(element.shadowRoot, roots);
+		}
+		if (element.tagName === 'IFRAME' || element.tagName === 'FRAME') {
+			let contentDocument = null;
+			try {
+				contentDocument = element.contentDocument;
+			} catch (e) {}
+			if (contentDocument) {
+				collectQueryRoots(contentDocument, roots);
+			}
+		}
+	});
+	return roots;
+}
+function deepQuerySelectorAll(query) {
+	return collectQueryRoots(document, []).flatMap(root => Array.from(root.querySelectorAll(query)));
+}
+function deepQuerySelector(query) {
+	for (const root of collectQueryRoots(document, [])) {
+		const element = root.querySelector(query);
+		if (element) {
+			return element;
+		}
+	}
+	return null;
+}
+`
+
+// Converts a DOM tree that was fetched with pierce enabled into an html tree. The documents of iframes are
+// inlined as html2md.FrameElement elements and open shadow roots as html2md.ShadowRootElement elements so
+// that translators can see the content and the boundaries.
+func convertDOMNode(n *cdp.Node) *html.Node {
+	switch n.NodeType {
+	case cdp.NodeTypeDocument, cdp.NodeTypeDocumentFragment:
+		doc := &html.Node{Type: html.DocumentNode}
+		appendConvertedChildren(doc, n.Children)
+		return doc
+	case cdp.NodeTypeDocumentType:
+		return &html.Node{Type: html.DoctypeNode, Data: n.NodeName}
+	case cdp.NodeTypeText, cdp.NodeTypeCDATA:
+		return &html.Node{Type: html.TextNode, Data: n.NodeValue}
+	case cdp.NodeTypeComment:
+		return &html.Node{Type: html.CommentNode, Data: n.NodeValue}
+	case cdp.NodeTypeElement:
+		tagName := n.LocalName
+		if tagName == "" {
+			tagName = strings.ToLower(n.NodeName)
+		}
+		element := &html.Node{Type: html.ElementNode, Data: tagName}
+		for i := 0; i+1 < len(n.Attributes); i += 2 {
+			element.Attr = append(element.Attr, html.Attribute{Key: n.Attributes[i], Val: n.Attributes[i+1]})
+		}
+		if tagName == "iframe" || tagName == "frame" {
+			element.Data = html2md.FrameElement
+			if n.ContentDocument == nil {
+				element.Attr = append(element.Attr, html.Attribute{Key: "data-cross-origin", Val: "true"})
+			} else if body := findDOMBody(n.ContentDocument); body != nil {
+				appendConvertedChildren(element, body.Children)
+			}
+			return element
+		}
+		for _, shadowRoot := range n.ShadowRoots {
+			if shadowRoot.ShadowRootType != cdp.ShadowRootTypeOpen {
+				continue
+			}
+			shadowElement := &html.Node{Type: html.ElementNode, Data: html2md.ShadowRootElement}
+			appendConvertedChildren(shadowElement, shadowRoot.Children)
+			element.AppendChild(shadowElement)
+		}
+		if voidElements[tagName] {
+			return element
+		}
+		if n.TemplateContent != nil {
+			appendConvertedChildren(element, n.TemplateContent.Children)
+		}
+		appendConvertedChildren(element, n.Children)
+		return element
+	default:
+		return nil
+	}
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"keygen": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+func appendConvertedChildren(parent *html.Node, children []*cdp.Node) {
+	for _, child := range children {
+		if converted := convertDOMNode(child); converted != nil {
+			if converted.Type == html.DocumentNode {
+				// document fragments are flattened into their parent
+				for c := converted.FirstChild; c != nil; {
+					next := c.NextSibling
+					converted.RemoveChild(c)
+					parent.AppendChild(c)
+					c = next
+				}
+			} else {
+				parent.AppendChild(converted)
+			}
+		}
+	}
+}
+
+func findDOMBody(n *cdp.Node) *cdp.Node {
+	if n.NodeType == cdp.NodeTypeElement && strings.EqualFold(n.NodeName, "body") {
+		return n
+	}
+	for _, child := range n.Children {
+		if body := findDOMBody(child); body != nil {
+			return body
+		}
+	}
+	return nil
+}
+
+func renderDOMNode(n *cdp.Node) (string, error) {
+	var sb strings.Builder
+	if err := html.Render(&sb, convertDOMNode(n)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
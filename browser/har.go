@@ -0,0 +1,222 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The types of a HAR 1.2 archive (http://www.softwareishard.com/blog/har-12-spec/) with the fields that the
+// network log records. Fields that start with an underscore are custom fields, as allowed by the spec.
+type harArchive struct {
+	Log *harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator *harCreator `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string       `json:"startedDateTime"`
+	Time            float64      `json:"time"`
+	Request         *harRequest  `json:"request"`
+	Response        *harResponse `json:"response"`
+	Cache           struct{}     `json:"cache"`
+	Timings         *harTimings  `json:"timings"`
+	TabID           string       `json:"_tabId"`
+	ResourceType    string       `json:"_resourceType,omitempty"`
+	Error           string       `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	HTTPVersion string          `json:"httpVersion"`
+	Cookies     []interface{}   `json:"cookies"`
+	Headers     []*harNameValue `json:"headers"`
+	QueryString []*harNameValue `json:"queryString"`
+	PostData    *harPostData    `json:"postData,omitempty"`
+	HeadersSize int64           `json:"headersSize"`
+	BodySize    int64           `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status       int64           `json:"status"`
+	StatusText   string          `json:"statusText"`
+	HTTPVersion  string          `json:"httpVersion"`
+	Cookies      []interface{}   `json:"cookies"`
+	Headers      []*harNameValue `json:"headers"`
+	Content      *harContent     `json:"content"`
+	RedirectURL  string          `json:"redirectURL"`
+	HeadersSize  int64           `json:"headersSize"`
+	BodySize     int64           `json:"bodySize"`
+	TransferSize int64           `json:"_transferSize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MIMEType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MIMEType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// The durations of the phases of a request in milliseconds, or -1 if a phase does not apply.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// Returns the network log of the session as a HAR 1.2 archive, which can be opened by the network panel of
+// browser developer tools.
+func (b *Browser) ExportHAR() ([]byte, error) {
+	entries := b.GetNetworkLog()
+	archive := &harArchive{
+		Log: &harLog{
+			Version: "1.2",
+			Creator: &harCreator{Name: "collaborativebrowser", Version: "1.0"},
+			Entries: make([]*harEntry, len(entries)),
+		},
+	}
+	for i, entry := range entries {
+		archive.Log.Entries[i] = newHAREntry(entry)
+	}
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding har: %w", err)
+	}
+	return data, nil
+}
+
+func newHAREntry(entry *NetworkEntry) *harEntry {
+	timings := newHARTimings(entry)
+	request := &harRequest{
+		Method:      entry.Method,
+		URL:         entry.URL,
+		HTTPVersion: entry.Protocol,
+		Cookies:     []interface{}{},
+		Headers:     newHARHeaders(entry.RequestHeaders),
+		QueryString: []*harNameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(entry.PostData)),
+	}
+	if u, err := url.Parse(entry.URL); err == nil {
+		for name, values := range u.Query() {
+			for _, value := range values {
+				request.QueryString = append(request.QueryString, &harNameValue{Name: name, Value: value})
+			}
+		}
+	}
+	if entry.PostData != "" {
+		request.PostData = &harPostData{MIMEType: headerValue(entry.RequestHeaders, "Content-Type"), Text: entry.PostData}
+	}
+	response := &harResponse{
+		Status:       entry.Status,
+		StatusText:   entry.StatusText,
+		HTTPVersion:  entry.Protocol,
+		Cookies:      []interface{}{},
+		Headers:      newHARHeaders(entry.ResponseHeaders),
+		Content:      &harContent{Size: entry.Size, MIMEType: entry.MIMEType, Text: entry.Body},
+		RedirectURL:  entry.RedirectURL,
+		HeadersSize:  -1,
+		BodySize:     -1,
+		TransferSize: entry.EncodedSize,
+	}
+	return &harEntry{
+		StartedDateTime: entry.StartTime.Format(time.RFC3339Nano),
+		Time:            timings.total(),
+		Request:         request,
+		Response:        response,
+		Timings:         timings,
+		TabID:           entry.TabID,
+		ResourceType:    entry.ResourceType,
+		Error:           entry.ErrorText,
+	}
+}
+
+func newHARHeaders(headers map[string]string) []*harNameValue {
+	harHeaders := []*harNameValue{}
+	for _, name := range sortedHeaderNames(headers) {
+		for _, value := range strings.Split(headers[name], "\n") {
+			harHeaders = append(harHeaders, &harNameValue{Name: name, Value: value})
+		}
+	}
+	return harHeaders
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// Splits the duration of a request into the phases of a HAR entry. The resource timing of a response is relative
+// to the start of the request, and the time after the headers were received is spent receiving the body.
+func newHARTimings(entry *NetworkEntry) *harTimings {
+	duration := float64(entry.Duration) / float64(time.Millisecond)
+	timing := entry.Timing
+	if timing == nil {
+		return &harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: duration}
+	}
+	phase := func(start, end float64) float64 {
+		if start < 0 || end < start {
+			return -1
+		}
+		return end - start
+	}
+	timings := &harTimings{
+		DNS:     phase(timing.DNSStart, timing.DNSEnd),
+		Connect: phase(timing.ConnectStart, timing.ConnectEnd),
+		SSL:     phase(timing.SslStart, timing.SslEnd),
+		Send:    phase(timing.SendStart, timing.SendEnd),
+		Wait:    phase(timing.SendEnd, timing.ReceiveHeadersEnd),
+	}
+	timings.Blocked = -1
+	for _, start := range []float64{timing.DNSStart, timing.ConnectStart, timing.SendStart} {
+		if start >= 0 {
+			timings.Blocked = start
+			break
+		}
+	}
+	timings.Send = max(timings.Send, 0)
+	timings.Wait = max(timings.Wait, 0)
+	timings.Receive = max(duration-max(timing.ReceiveHeadersEnd, 0), 0)
+	return timings
+}
+
+// Returns the total time of the request, which the HAR spec defines as the sum of its phases except ssl, since
+// ssl is part of connect.
+func (t *harTimings) total() float64 {
+	total := 0.0
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if phase > 0 {
+			total += phase
+		}
+	}
+	return total
+}
@@ -0,0 +1,145 @@
+package browser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestNewHARTimings(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *NetworkEntry
+		want  harTimings
+	}{
+		{
+			name:  "without resource timing",
+			entry: &NetworkEntry{Duration: 120 * time.Millisecond},
+			want:  harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: 120},
+		},
+		{
+			name: "new connection",
+			entry: &NetworkEntry{
+				Duration: 100 * time.Millisecond,
+				Timing: &network.ResourceTiming{
+					DNSStart: 2, DNSEnd: 10,
+					ConnectStart: 10, ConnectEnd: 30,
+					SslStart: 15, SslEnd: 30,
+					SendStart: 30, SendEnd: 31,
+					ReceiveHeadersEnd: 80,
+				},
+			},
+			want: harTimings{Blocked: 2, DNS: 8, Connect: 20, SSL: 15, Send: 1, Wait: 49, Receive: 20},
+		},
+		{
+			name: "reused connection",
+			entry: &NetworkEntry{
+				Duration: 50 * time.Millisecond,
+				Timing: &network.ResourceTiming{
+					DNSStart: -1, DNSEnd: -1,
+					ConnectStart: -1, ConnectEnd: -1,
+					SslStart: -1, SslEnd: -1,
+					SendStart: 1, SendEnd: 1,
+					ReceiveHeadersEnd: 40,
+				},
+			},
+			want: harTimings{Blocked: 1, DNS: -1, Connect: -1, SSL: -1, Send: 0, Wait: 39, Receive: 10},
+		},
+		{
+			name: "failed before headers",
+			entry: &NetworkEntry{
+				Duration: 5 * time.Millisecond,
+				Timing: &network.ResourceTiming{
+					DNSStart: 0, DNSEnd: 20,
+					ConnectStart: -1, ConnectEnd: -1,
+					SslStart: -1, SslEnd: -1,
+					SendStart: -1, SendEnd: -1,
+					ReceiveHeadersEnd: -1,
+				},
+			},
+			want: harTimings{Blocked: 0, DNS: 20, Connect: -1, SSL: -1, Send: 0, Wait: 0, Receive: 5},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := newHARTimings(test.entry); *got != test.want {
+				t.Errorf("newHARTimings() = %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
+
+func TestHARTimingsTotal(t *testing.T) {
+	tests := []struct {
+		timings harTimings
+		want    float64
+	}{
+		{harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: 120}, 120},
+		// ssl is part of connect, so it is not counted twice
+		{harTimings{Blocked: 2, DNS: 8, Connect: 20, SSL: 15, Send: 1, Wait: 49, Receive: 20}, 100},
+		{harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}, 0},
+	}
+	for _, test := range tests {
+		if got := test.timings.total(); got != test.want {
+			t.Errorf("%+v.total() = %g, want %g", test.timings, got, test.want)
+		}
+	}
+}
+
+func TestNewHAREntry(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry := newHAREntry(&NetworkEntry{
+		TabID:           "tab-0",
+		URL:             "https://example.com/api/items?q=shoes&tag=a&tag=b",
+		Method:          "POST",
+		ResourceType:    "Fetch",
+		RequestHeaders:  map[string]string{"content-type": "application/json", "Accept": "*/*"},
+		PostData:        `{"page":1}`,
+		StartTime:       start,
+		Duration:        30 * time.Millisecond,
+		Status:          200,
+		StatusText:      "OK",
+		MIMEType:        "application/json",
+		Protocol:        "h2",
+		ResponseHeaders: map[string]string{"Set-Cookie": "a=1\nb=2"},
+		Size:            12,
+		EncodedSize:     340,
+		Body:            `{"items":[]}`,
+		Finished:        true,
+	})
+	if entry.StartedDateTime != "2024-01-02T03:04:05Z" {
+		t.Errorf("startedDateTime = %s", entry.StartedDateTime)
+	}
+	if entry.Time != 30 {
+		t.Errorf("time = %g, want 30", entry.Time)
+	}
+	if got := entry.Request.PostData; got == nil || got.MIMEType != "application/json" || got.Text != `{"page":1}` {
+		t.Errorf("postData = %+v", got)
+	}
+	if entry.Request.BodySize != 10 {
+		t.Errorf("request bodySize = %d, want 10", entry.Request.BodySize)
+	}
+	queries := map[string]int{}
+	for _, query := range entry.Request.QueryString {
+		queries[query.Name+"="+query.Value]++
+	}
+	for _, want := range []string{"q=shoes", "tag=a", "tag=b"} {
+		if queries[want] != 1 {
+			t.Errorf("queryString = %v, want %s once", queries, want)
+		}
+	}
+	if headers := entry.Request.Headers; len(headers) != 2 || headers[0].Name != "Accept" || headers[1].Name != "content-type" {
+		t.Errorf("request headers are not sorted by name: %+v", headers)
+	}
+	// headers that were sent more than once are joined by newlines in the log and split again in the archive
+	if headers := entry.Response.Headers; len(headers) != 2 || headers[0].Value != "a=1" || headers[1].Value != "b=2" {
+		t.Errorf("response headers = %+v", headers)
+	}
+	if content := entry.Response.Content; content.Size != 12 || content.MIMEType != "application/json" || content.Text != `{"items":[]}` {
+		t.Errorf("content = %+v", content)
+	}
+	if entry.Response.TransferSize != 340 || entry.Response.HTTPVersion != "h2" {
+		t.Errorf("response = %+v", entry.Response)
+	}
+}
@@ -0,0 +1,258 @@
+package browser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+var heavyResourceTypes = map[network.ResourceType]bool{
+	network.ResourceTypeImage: true,
+	network.ResourceTypeFont:  true,
+	network.ResourceTypeMedia: true,
+}
+
+type extraHeader struct {
+	domain string
+	name   string
+	value  string
+}
+
+type requestInterceptor struct {
+	blockHeavyResources bool
+	blockedDomains      map[string]bool
+	blockedPatterns     []*regexp.Regexp
+	extraHeaders        []*extraHeader
+	// the main frame documents that this policy does not permit are failed before they load
+	navigationPolicy *NavigationPolicy
+	// set while another part of the browser intercepts requests, such as when storage is restored
+	suspended atomic.Bool
+
+	mu sync.Mutex
+	// the navigations that were failed by tab id and have not been reported yet
+	blockedNavigations map[string][]*NavigationBlockedError
+}
+
+// Builds a request interceptor from the launch config and the navigation policy. Returns nil if neither enables
+// interception.
+func newRequestInterceptor(config *LaunchConfig, policy *NavigationPolicy) (*requestInterceptor, error) {
+	if config.BlockList == "" && !config.BlockHeavyResources && len(config.ExtraHeaders) == 0 && policy == nil {
+		return nil, nil
+	}
+	interceptor := &requestInterceptor{
+		blockHeavyResources: config.BlockHeavyResources,
+		blockedDomains:      make(map[string]bool),
+		navigationPolicy:    policy,
+		blockedNavigations:  make(map[string][]*NavigationBlockedError),
+	}
+	if config.BlockList != "" {
+		if err := interceptor.loadBlockList(config.BlockList); err != nil {
+			return nil, err
+		}
+	}
+	for _, header := range config.ExtraHeaders {
+		interceptor.extraHeaders = append(interceptor.extraHeaders, &extraHeader{
+			domain: strings.ToLower(strings.TrimSpace(header.Domain)),
+			name:   strings.TrimSpace(header.Name),
+			value:  strings.TrimSpace(header.Value),
+		})
+	}
+	return interceptor, nil
+}
+
+func (i *requestInterceptor) loadBlockList(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening block list %s: %w", path, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		if strings.HasPrefix(line, "||") {
+			line = strings.TrimSuffix(strings.TrimPrefix(line, "||"), "^")
+		}
+		if strings.ContainsAny(line, "*/") {
+			pattern := regexp.QuoteMeta(line)
+			pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid block list pattern %s: %w", line, err)
+			}
+			i.blockedPatterns = append(i.blockedPatterns, re)
+		} else {
+			i.blockedDomains[strings.ToLower(line)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading block list %s: %w", path, err)
+	}
+	return nil
+}
+
+// Reports whether the host is the domain or one of its subdomains.
+func matchesDomain(host string, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func (i *requestInterceptor) isBlocked(resourceType network.ResourceType, requestURL string) bool {
+	if i.blockHeavyResources && heavyResourceTypes[resourceType] {
+		return true
+	}
+	if u, err := url.Parse(requestURL); err == nil {
+		host := strings.ToLower(u.Hostname())
+		for labels := host; labels != ""; {
+			if i.blockedDomains[labels] {
+				return true
+			}
+			_, rest, ok := strings.Cut(labels, ".")
+			if !ok {
+				break
+			}
+			labels = rest
+		}
+	}
+	for _, pattern := range i.blockedPatterns {
+		if pattern.MatchString(requestURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the headers of the request with the extra headers for its domain, or nil if there are none.
+func (i *requestInterceptor) headersFor(request *network.Request) []*fetch.HeaderEntry {
+	u, err := url.Parse(request.URL)
+	if err != nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	extra := []*extraHeader{}
+	for _, header := range i.extraHeaders {
+		if matchesDomain(host, header.domain) {
+			extra = append(extra, header)
+		}
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	headers := []*fetch.HeaderEntry{}
+	for name, value := range request.Headers {
+		overridden := false
+		for _, header := range extra {
+			if strings.EqualFold(name, header.name) {
+				overridden = true
+			}
+		}
+		if !overridden {
+			headers = append(headers, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+		}
+	}
+	for _, header := range extra {
+		headers = append(headers, &fetch.HeaderEntry{Name: header.name, Value: header.value})
+	}
+	return headers
+}
+
+// Reports whether the interceptor only enforces the navigation policy, in which case only documents are intercepted.
+func (i *requestInterceptor) onlyChecksNavigations() bool {
+	return !i.blockHeavyResources && len(i.blockedDomains) == 0 && len(i.blockedPatterns) == 0 && len(i.extraHeaders) == 0
+}
+
+// Returns a *NavigationBlockedError if the paused request loads a document into the main frame of the page target
+// at a url that the navigation policy does not permit. The main frame of a page has the id of its target.
+func (i *requestInterceptor) checkNavigation(targetID target.ID, paused *fetch.EventRequestPaused) error {
+	if paused.ResourceType != network.ResourceTypeDocument || paused.FrameID != cdp.FrameID(targetID) {
+		return nil
+	}
+	var blocked *NavigationBlockedError
+	if err := i.navigationPolicy.Check(paused.Request.URL + paused.Request.URLFragment); errors.As(err, &blocked) {
+		return blocked
+	}
+	return nil
+}
+
+func (i *requestInterceptor) recordBlockedNavigation(t *tab, blocked *NavigationBlockedError) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.blockedNavigations[t.id] = append(i.blockedNavigations[t.id], blocked)
+}
+
+// Returns and clears the navigations that were failed in the tab. The interceptor may be nil.
+func (i *requestInterceptor) takeBlockedNavigations(t *tab) []*NavigationBlockedError {
+	if i == nil {
+		return nil
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	blocked := i.blockedNavigations[t.id]
+	delete(i.blockedNavigations, t.id)
+	return blocked
+}
+
+func (i *requestInterceptor) handleEvent(t *tab, ev interface{}) {
+	paused, ok := ev.(*fetch.EventRequestPaused)
+	if !ok || i.suspended.Load() {
+		return
+	}
+	// event handlers must not block, so the request is resumed in a separate goroutine
+	go func() {
+		c := chromedp.FromContext(t.ctx)
+		executorCtx := cdp.WithExecutor(t.ctx, c.Target)
+		var err error
+		var blocked *NavigationBlockedError
+		if errors.As(i.checkNavigation(c.Target.TargetID, paused), &blocked) {
+			i.recordBlockedNavigation(t, blocked)
+			err = fetch.FailRequest(paused.RequestID, network.ErrorReasonBlockedByClient).Do(executorCtx)
+		} else if i.isBlocked(paused.ResourceType, paused.Request.URL) {
+			err = fetch.FailRequest(paused.RequestID, network.ErrorReasonBlockedByClient).Do(executorCtx)
+		} else if headers := i.headersFor(paused.Request); headers != nil {
+			err = fetch.ContinueRequest(paused.RequestID).WithHeaders(headers).Do(executorCtx)
+		} else {
+			err = fetch.ContinueRequest(paused.RequestID).Do(executorCtx)
+		}
+		if err != nil {
+			log.Printf("error resuming intercepted request %s: %v", paused.Request.URL, err)
+		}
+	}()
+}
+
+// Enables request interception in a tab, or disables it if the browser does not intercept requests.
+func (b *Browser) enableRequestInterception(t *tab) error {
+	if b.interceptor == nil {
+		return chromedp.Run(t.ctx, fetch.Disable())
+	}
+	pattern := &fetch.RequestPattern{URLPattern: "*"}
+	if b.interceptor.onlyChecksNavigations() {
+		pattern.ResourceType = network.ResourceTypeDocument
+	}
+	return chromedp.Run(t.ctx, fetch.Enable().WithPatterns([]*fetch.RequestPattern{pattern}))
+}
+
+func (b *Browser) listenForInterceptedRequests(t *tab) {
+	if b.interceptor == nil {
+		return
+	}
+	chromedp.ListenTarget(t.ctx, func(ev interface{}) {
+		b.interceptor.handleEvent(t, ev)
+	})
+	if err := b.enableRequestInterception(t); err != nil {
+		log.Printf("error enabling request interception in %s: %v", t.id, err)
+	}
+}
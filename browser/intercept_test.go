@@ -0,0 +1,128 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func writeBlockList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRequestInterceptorIsBlocked(t *testing.T) {
+	blockList := writeBlockList(t, `# trackers
+tracker.com
+||Ads.Example.net^
+! adblock comment
+
+*/analytics.js
+https://cdn.example.org/*/pixel.gif
+`)
+	interceptor, err := newRequestInterceptor(&LaunchConfig{BlockList: blockList}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		resourceType network.ResourceType
+		url          string
+		want         bool
+	}{
+		{"listed domain", network.ResourceTypeScript, "https://tracker.com/t.js", true},
+		{"subdomain of listed domain", network.ResourceTypeXHR, "https://eu.api.tracker.com/collect", true},
+		{"domain with listed suffix", network.ResourceTypeScript, "https://nottracker.com/t.js", false},
+		{"listed domain in path", network.ResourceTypeScript, "https://example.com/tracker.com", false},
+		{"adblock rule", network.ResourceTypeImage, "https://ads.example.net/banner.png", true},
+		{"subdomain of adblock rule", network.ResourceTypeImage, "https://x.ads.example.net/banner.png", true},
+		{"parent of adblock rule", network.ResourceTypeDocument, "https://example.net/", false},
+		{"wildcard pattern", network.ResourceTypeScript, "https://example.com/js/analytics.js", true},
+		{"url pattern", network.ResourceTypeImage, "https://cdn.example.org/v2/pixel.gif", true},
+		{"url pattern with other path", network.ResourceTypeImage, "https://cdn.example.org/v2/logo.gif", false},
+		{"unlisted url", network.ResourceTypeDocument, "https://example.com/", false},
+		{"heavy resources are not blocked", network.ResourceTypeImage, "https://example.com/photo.jpg", false},
+	}
+	for _, test := range tests {
+		if got := interceptor.isBlocked(test.resourceType, test.url); got != test.want {
+			t.Errorf("%s: isBlocked(%s, %q) = %t, want %t", test.name, test.resourceType, test.url, got, test.want)
+		}
+	}
+}
+
+func TestRequestInterceptorBlocksHeavyResources(t *testing.T) {
+	interceptor, err := newRequestInterceptor(&LaunchConfig{BlockHeavyResources: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resourceType network.ResourceType
+		want         bool
+	}{
+		{network.ResourceTypeImage, true},
+		{network.ResourceTypeFont, true},
+		{network.ResourceTypeMedia, true},
+		{network.ResourceTypeDocument, false},
+		{network.ResourceTypeStylesheet, false},
+		{network.ResourceTypeScript, false},
+		{network.ResourceTypeXHR, false},
+	}
+	for _, test := range tests {
+		if got := interceptor.isBlocked(test.resourceType, "https://example.com/resource"); got != test.want {
+			t.Errorf("isBlocked(%s) = %t, want %t", test.resourceType, got, test.want)
+		}
+	}
+}
+
+func TestNewRequestInterceptor(t *testing.T) {
+	if interceptor, err := newRequestInterceptor(&LaunchConfig{}, nil); err != nil || interceptor != nil {
+		t.Errorf("newRequestInterceptor of an empty config = %v, %v, want nil", interceptor, err)
+	}
+	if _, err := newRequestInterceptor(&LaunchConfig{BlockList: filepath.Join(t.TempDir(), "missing.txt")}, nil); err == nil {
+		t.Errorf("newRequestInterceptor with a missing block list succeeded")
+	}
+	if interceptor, err := newRequestInterceptor(&LaunchConfig{BlockHeavyResources: true}, nil); err != nil || interceptor.onlyChecksNavigations() {
+		t.Errorf("newRequestInterceptor that blocks heavy resources = %v, %v, want an interceptor that intercepts all requests", interceptor, err)
+	}
+}
+
+func TestRequestInterceptorHeadersFor(t *testing.T) {
+	interceptor, err := newRequestInterceptor(&LaunchConfig{ExtraHeaders: []*ExtraHeader{
+		{Domain: "api.example.com", Name: "Authorization", Value: "Bearer token"},
+		{Domain: " Example.com ", Name: "X-Debug", Value: " 1 "},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		url     string
+		headers network.Headers
+		want    map[string]string
+	}{
+		{"other domain", "https://example.org/", network.Headers{"Accept": "*/*"}, nil},
+		{"domain", "https://example.com/", network.Headers{"Accept": "*/*"}, map[string]string{"Accept": "*/*", "X-Debug": "1"}},
+		{"subdomain with its own header", "https://api.example.com/v1", network.Headers{"authorization": "Basic old"}, map[string]string{"Authorization": "Bearer token", "X-Debug": "1"}},
+		{"unparsable url", "http://[::1", nil, nil},
+	}
+	for _, test := range tests {
+		entries := interceptor.headersFor(&network.Request{URL: test.url, Headers: test.headers})
+		var got map[string]string
+		if entries != nil {
+			got = make(map[string]string)
+			for _, entry := range entries {
+				got[entry.Name] = entry.Value
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: headersFor(%q) = %v, want %v", test.name, test.url, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// Special keys that can be pressed by name, keyed by lowercase name.
+var namedKeys = map[string]string{
+	"enter":      kb.Enter,
+	"return":     kb.Enter,
+	"tab":        kb.Tab,
+	"escape":     kb.Escape,
+	"esc":        kb.Escape,
+	"backspace":  kb.Backspace,
+	"delete":     kb.Delete,
+	"space":      " ",
+	"arrowup":    kb.ArrowUp,
+	"arrowdown":  kb.ArrowDown,
+	"arrowleft":  kb.ArrowLeft,
+	"arrowright": kb.ArrowRight,
+	"up":         kb.ArrowUp,
+	"down":       kb.ArrowDown,
+	"left":       kb.ArrowLeft,
+	"right":      kb.ArrowRight,
+	"home":       kb.Home,
+	"end":        kb.End,
+	"pageup":     kb.PageUp,
+	"pagedown":   kb.PageDown,
+}
+
+var modifierKeys = map[string]input.Modifier{
+	"alt":     input.ModifierAlt,
+	"option":  input.ModifierAlt,
+	"control": input.ModifierCtrl,
+	"ctrl":    input.ModifierCtrl,
+	"meta":    input.ModifierMeta,
+	"cmd":     input.ModifierMeta,
+	"command": input.ModifierMeta,
+	"shift":   input.ModifierShift,
+}
+
+// Parses a key combination such as "Enter", "Shift+Tab", or "Control+a" into the key to dispatch and its modifiers.
+func ParseKeyCombination(combination string) (key string, modifiers []input.Modifier, err error) {
+	if strings.TrimSpace(combination) == "" {
+		return "", nil, errors.New("key cannot be empty")
+	}
+	parts := strings.Split(combination, "+")
+	// a trailing "+" means that the plus key itself was requested, e.g. "Shift++"
+	if strings.HasSuffix(combination, "++") || combination == "+" {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+	for _, part := range parts[:len(parts)-1] {
+		modifier, ok := modifierKeys[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported modifier key: %s", part)
+		}
+		modifiers = append(modifiers, modifier)
+	}
+	name := parts[len(parts)-1]
+	if trimmed := strings.TrimSpace(name); trimmed != "" {
+		name = trimmed
+	}
+	if namedKey, ok := namedKeys[strings.ToLower(name)]; ok {
+		return namedKey, modifiers, nil
+	} else if utf8.RuneCountInString(name) == 1 {
+		return name, modifiers, nil
+	}
+	return "", nil, fmt.Errorf("unsupported key: %s", name)
+}
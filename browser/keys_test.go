@@ -0,0 +1,56 @@
+package browser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp/kb"
+)
+
+func TestParseKeyCombination(t *testing.T) {
+	tests := []struct {
+		combination string
+		key         string
+		modifiers   []input.Modifier
+		wantErr     bool
+	}{
+		{"Enter", kb.Enter, nil, false},
+		{"a", "a", nil, false},
+		{"A", "A", nil, false},
+		{"Shift+Tab", kb.Tab, []input.Modifier{input.ModifierShift}, false},
+		{"Control+a", "a", []input.Modifier{input.ModifierCtrl}, false},
+		{"Ctrl+Shift+ArrowLeft", kb.ArrowLeft, []input.Modifier{input.ModifierCtrl, input.ModifierShift}, false},
+		{"Cmd+Alt+Delete", kb.Delete, []input.Modifier{input.ModifierMeta, input.ModifierAlt}, false},
+		{"Command+Option+Esc", kb.Escape, []input.Modifier{input.ModifierMeta, input.ModifierAlt}, false},
+		{"Meta+c", "c", []input.Modifier{input.ModifierMeta}, false},
+		{"return", kb.Enter, nil, false},
+		{"ENTER", kb.Enter, nil, false},
+		{"pageDown", kb.PageDown, nil, false},
+		{"up", kb.ArrowUp, nil, false},
+		{"space", " ", nil, false},
+		{"ctrl + shift + z", "z", []input.Modifier{input.ModifierCtrl, input.ModifierShift}, false},
+		{"+", "+", nil, false},
+		{"Shift++", "+", []input.Modifier{input.ModifierShift}, false},
+		{"é", "é", nil, false},
+		{"", "", nil, true},
+		{"   ", "", nil, true},
+		{"Ctrl+", "", nil, true},
+		{"Foo+A", "", nil, true},
+		{"Ctrl+Foo", "", nil, true},
+		{"Enterr", "", nil, true},
+		{"ab", "", nil, true},
+	}
+	for _, test := range tests {
+		key, modifiers, err := ParseKeyCombination(test.combination)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseKeyCombination(%q) error = %v, want error %t", test.combination, err, test.wantErr)
+			continue
+		} else if test.wantErr {
+			continue
+		}
+		if key != test.key || !reflect.DeepEqual(modifiers, test.modifiers) {
+			t.Errorf("ParseKeyCombination(%q) = %q, %v, want %q, %v", test.combination, key, modifiers, test.key, test.modifiers)
+		}
+	}
+}
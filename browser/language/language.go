@@ -5,4 +5,6 @@ type Language string
 const (
 	LanguageHTML Language = "html"
 	LanguageMD   Language = "md"
+	// The accessibility tree that is computed by the browser, rendered as a nested markdown list.
+	LanguageAXTree Language = "ax"
 )
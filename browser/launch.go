@@ -0,0 +1,191 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chromedp/chromedp"
+)
+
+// The user agent of a desktop Chrome, which is used instead of the user agent of headless Chrome so that
+// pages are served as they are to users.
+const DefaultUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36"
+
+// Configures how the browser is launched. The zero value, which is also used if no config is given, launches a
+// headless Chrome with the default options of chromedp; DefaultLaunchConfig is a better starting point for most
+// uses.
+type LaunchConfig struct {
+	Headful bool `json:"headful,omitempty"`
+	// The path of the Chrome binary; chromedp looks for one if it is empty.
+	ExecPath string `json:"execPath,omitempty"`
+	// The profile directory of the browser, which keeps cookies and storage between runs; a temporary directory
+	// is used if it is empty.
+	UserDataDir string `json:"userDataDir,omitempty"`
+	// The proxy server for all requests, such as "http://localhost:8080" or "socks5://localhost:1080".
+	Proxy     string      `json:"proxy,omitempty"`
+	UserAgent string      `json:"userAgent,omitempty"`
+	Window    *WindowSize `json:"window,omitempty"`
+	// The language of the browser, such as "en-US", which is also sent in the Accept-Language header.
+	Locale string `json:"locale,omitempty"`
+	// The time zone of the browser, such as "America/New_York".
+	Timezone string `json:"timezone,omitempty"`
+	// Whether to hide the infobar and the navigator.webdriver flag that tell users and pages that the browser is
+	// automated.
+	DisableAutomationMessage bool `json:"disableAutomationMessage,omitempty"`
+	// Additional command line flags, such as "disable-gpu" or "--force-device-scale-factor=2".
+	ExtraFlags []string `json:"extraFlags,omitempty"`
+	// The directories of unpacked extensions to load. Extensions are only loaded by headful browsers.
+	Extensions []string `json:"extensions,omitempty"`
+
+	// A file of domains and url patterns to block requests to. Each line of the file is a domain, which also
+	// matches its subdomains, an adblock style "||domain^" rule, or a url pattern with "*" wildcards. Empty lines
+	// and lines that start with "#" or "!" are ignored.
+	BlockList string `json:"blockList,omitempty"`
+	// Whether to block images, fonts, and media, which speeds up headless runs.
+	BlockHeavyResources bool `json:"blockHeavyResources,omitempty"`
+	// Headers to add to the requests to a domain and its subdomains.
+	ExtraHeaders []*ExtraHeader `json:"extraHeaders,omitempty"`
+}
+
+type WindowSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type ExtraHeader struct {
+	Domain string `json:"domain"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+}
+
+// Returns a launch config for a headless browser that presents itself as a desktop Chrome and does not announce
+// that it is automated. Fields can be changed before it is used, and LoadLaunchConfig starts from it.
+func DefaultLaunchConfig() *LaunchConfig {
+	return &LaunchConfig{
+		UserAgent:                DefaultUserAgent,
+		DisableAutomationMessage: true,
+	}
+}
+
+// Reads a launch config from a JSON file. Fields that are not in the file keep their default values.
+func LoadLaunchConfig(path string) (*LaunchConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading launch config %s: %w", path, err)
+	}
+	config := DefaultLaunchConfig()
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error parsing launch config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Returns a deep copy of the config, so that changing the copy does not change the config.
+func (c *LaunchConfig) clone() *LaunchConfig {
+	clone := *c
+	if c.Window != nil {
+		window := *c.Window
+		clone.Window = &window
+	}
+	clone.ExtraFlags = append([]string(nil), c.ExtraFlags...)
+	clone.Extensions = append([]string(nil), c.Extensions...)
+	if c.ExtraHeaders != nil {
+		clone.ExtraHeaders = make([]*ExtraHeader, len(c.ExtraHeaders))
+		for i, header := range c.ExtraHeaders {
+			h := *header
+			clone.ExtraHeaders[i] = &h
+		}
+	}
+	return &clone
+}
+
+func (c *LaunchConfig) validate() error {
+	if c.Window != nil && (c.Window.Width <= 0 || c.Window.Height <= 0) {
+		return fmt.Errorf("invalid window size: %dx%d", c.Window.Width, c.Window.Height)
+	}
+	for _, header := range c.ExtraHeaders {
+		if header.Domain == "" || header.Name == "" {
+			return fmt.Errorf("invalid extra header %q for domain %q", header.Name, header.Domain)
+		}
+	}
+	return nil
+}
+
+func buildOptions(config *LaunchConfig) ([]chromedp.ExecAllocatorOption, error) {
+	ops := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	if config.Headful {
+		ops = append(ops, chromedp.Flag("headless", false))
+	}
+	if config.ExecPath != "" {
+		ops = append(ops, chromedp.ExecPath(config.ExecPath))
+	}
+	if config.UserDataDir != "" {
+		ops = append(ops, chromedp.UserDataDir(config.UserDataDir))
+	}
+	if config.Proxy != "" {
+		ops = append(ops, chromedp.ProxyServer(config.Proxy))
+	}
+	if config.UserAgent != "" {
+		ops = append(ops, chromedp.UserAgent(config.UserAgent))
+	}
+	if config.Window != nil {
+		ops = append(ops, chromedp.WindowSize(config.Window.Width, config.Window.Height))
+	}
+	if config.Locale != "" {
+		ops = append(ops, chromedp.Flag("lang", config.Locale), chromedp.Flag("accept-lang", config.Locale))
+	}
+	if config.Timezone != "" {
+		ops = append(ops, chromedp.Env("TZ="+config.Timezone))
+	}
+	if config.DisableAutomationMessage {
+		// the infobar is shown because of enable-automation, and navigator.webdriver is set by the
+		// AutomationControlled blink feature
+		ops = append(ops,
+			chromedp.Flag("enable-automation", false),
+			chromedp.Flag("disable-blink-features", "AutomationControlled"),
+		)
+	}
+	if len(config.Extensions) > 0 {
+		dirs := make([]string, len(config.Extensions))
+		for i, dir := range config.Extensions {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving extension directory %s: %w", dir, err)
+			}
+			dirs[i] = abs
+		}
+		ops = append(ops,
+			chromedp.Flag("disable-extensions", false),
+			chromedp.Flag("disable-extensions-except", strings.Join(dirs, ",")),
+			chromedp.Flag("load-extension", strings.Join(dirs, ",")),
+		)
+	}
+	for _, flag := range config.ExtraFlags {
+		name, value := parseFlag(flag)
+		if name == "" {
+			return nil, fmt.Errorf("invalid extra flag: %q", flag)
+		}
+		ops = append(ops, chromedp.Flag(name, value))
+	}
+	return ops, nil
+}
+
+// Parses a command line flag such as "--name=value" or "name" into its name and its value, which is true if
+// the flag has no value.
+func parseFlag(flag string) (string, interface{}) {
+	name, value, ok := strings.Cut(strings.TrimLeft(strings.TrimSpace(flag), "-"), "=")
+	if !ok {
+		return name, true
+	}
+	switch value {
+	case "true":
+		return name, true
+	case "false":
+		return name, false
+	default:
+		return name, value
+	}
+}
@@ -0,0 +1,143 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestParseFlag(t *testing.T) {
+	tests := []struct {
+		flag  string
+		name  string
+		value interface{}
+	}{
+		{"--a=b", "a", "b"},
+		{"-a=b", "a", "b"},
+		{"a=b", "a", "b"},
+		{"--flag", "flag", true},
+		{"disable-gpu", "disable-gpu", true},
+		{"  --padded  ", "padded", true},
+		{"--js-flags=--max-old-space-size=4096", "js-flags", "--max-old-space-size=4096"},
+		{"--host-rules=MAP * 127.0.0.1", "host-rules", "MAP * 127.0.0.1"},
+		{"--enabled=true", "enabled", true},
+		{"--enabled=false", "enabled", false},
+		{"--empty=", "empty", ""},
+		{"--", "", true},
+		{"--=value", "", "value"},
+		{"", "", true},
+	}
+	for _, test := range tests {
+		name, value := parseFlag(test.flag)
+		if name != test.name || !reflect.DeepEqual(value, test.value) {
+			t.Errorf("parseFlag(%q) = %q, %#v, want %q, %#v", test.flag, name, value, test.name, test.value)
+		}
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	defaults := len(chromedp.DefaultExecAllocatorOptions)
+	tests := []struct {
+		name    string
+		config  *LaunchConfig
+		options int
+		wantErr bool
+	}{
+		{"zero config", &LaunchConfig{}, defaults, false},
+		{"default config", DefaultLaunchConfig(), defaults + 3, false},
+		{"headful with window and locale", &LaunchConfig{Headful: true, Window: &WindowSize{Width: 800, Height: 600}, Locale: "de-DE"}, defaults + 4, false},
+		{"extensions", &LaunchConfig{Extensions: []string{"a", "b"}}, defaults + 3, false},
+		{"extra flags", &LaunchConfig{ExtraFlags: []string{"--a=b", "flag"}}, defaults + 2, false},
+		{"empty extra flag", &LaunchConfig{ExtraFlags: []string{"--"}}, 0, true},
+		{"extra flag without name", &LaunchConfig{ExtraFlags: []string{"=value"}}, 0, true},
+	}
+	for _, test := range tests {
+		options, err := buildOptions(test.config)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: buildOptions error = %v, want error %t", test.name, err, test.wantErr)
+		} else if !test.wantErr && len(options) != test.options {
+			t.Errorf("%s: buildOptions returned %d options, want %d", test.name, len(options), test.options)
+		}
+	}
+}
+
+func TestLaunchConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *LaunchConfig
+		wantErr bool
+	}{
+		{"zero config", &LaunchConfig{}, false},
+		{"default config", DefaultLaunchConfig(), false},
+		{"window", &LaunchConfig{Window: &WindowSize{Width: 1280, Height: 800}}, false},
+		{"empty window", &LaunchConfig{Window: &WindowSize{}}, true},
+		{"negative window height", &LaunchConfig{Window: &WindowSize{Width: 1280, Height: -1}}, true},
+		{"extra header", &LaunchConfig{ExtraHeaders: []*ExtraHeader{{Domain: "example.com", Name: "X-Test", Value: ""}}}, false},
+		{"extra header without domain", &LaunchConfig{ExtraHeaders: []*ExtraHeader{{Name: "X-Test", Value: "1"}}}, true},
+		{"extra header without name", &LaunchConfig{ExtraHeaders: []*ExtraHeader{{Domain: "example.com", Value: "1"}}}, true},
+	}
+	for _, test := range tests {
+		if err := test.config.validate(); (err != nil) != test.wantErr {
+			t.Errorf("%s: validate() = %v, want error %t", test.name, err, test.wantErr)
+		}
+	}
+}
+
+func TestNewBrowserWithDefaultsRejectsInvalidConfigs(t *testing.T) {
+	if _, err := newBrowserWithDefaults(nil, &LaunchConfig{Window: &WindowSize{}}); err == nil {
+		t.Errorf("newBrowserWithDefaults with an empty window succeeded")
+	}
+	if b, err := newBrowserWithDefaults(nil, nil); err != nil || !reflect.DeepEqual(b.launchConfig, &LaunchConfig{}) {
+		t.Errorf("newBrowserWithDefaults with a nil config = %v, want the zero config", err)
+	}
+}
+
+func TestLoadLaunchConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	config, err := LoadLaunchConfig(write("config.json", `{"headful": true, "extraFlags": ["--a=b"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultLaunchConfig()
+	want.Headful = true
+	want.ExtraFlags = []string{"--a=b"}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("LoadLaunchConfig = %+v, want %+v", config, want)
+	}
+	if _, err := LoadLaunchConfig(write("invalid.json", `{"headful": "yes"}`)); err == nil {
+		t.Errorf("LoadLaunchConfig of an invalid file succeeded")
+	}
+	if _, err := LoadLaunchConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Errorf("LoadLaunchConfig of a missing file succeeded")
+	}
+}
+
+func TestLaunchConfigClone(t *testing.T) {
+	config := &LaunchConfig{
+		Window:       &WindowSize{Width: 800, Height: 600},
+		ExtraFlags:   []string{"a"},
+		Extensions:   []string{"ext"},
+		ExtraHeaders: []*ExtraHeader{{Domain: "example.com", Name: "X-Test", Value: "1"}},
+	}
+	clone := config.clone()
+	if !reflect.DeepEqual(clone, config) {
+		t.Fatalf("clone() = %+v, want %+v", clone, config)
+	}
+	clone.Window.Width = 1
+	clone.ExtraFlags[0] = "b"
+	clone.Extensions[0] = "other"
+	clone.ExtraHeaders[0].Value = "2"
+	if config.Window.Width != 800 || config.ExtraFlags[0] != "a" || config.Extensions[0] != "ext" || config.ExtraHeaders[0].Value != "1" {
+		t.Errorf("changing the clone changed the config: %+v", config)
+	}
+}
@@ -0,0 +1,242 @@
+package browser
+
+import (
+	"bytes"
+	"collaborativebrowser/llm"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+const (
+	// The token budget of the response bodies that inspect_network returns if none is given, and the largest
+	// budget that can be given.
+	DefaultInspectNetworkMaxTokens = 2000
+	MaxInspectNetworkMaxTokens     = 8000
+	// The most API responses of an action that are listed in its observation.
+	maxAPIResponseSummaryEntries = 5
+	// How long the summary of an action waits for response bodies that are still being fetched.
+	apiResponseBodyTimeout = time.Second
+)
+
+// Whether the entry is a fetch or XHR call of a page to its own origin, which is how pages load their data.
+func (e *NetworkEntry) isAPIRequest() bool {
+	if e.ResourceType != string(network.ResourceTypeFetch) && e.ResourceType != string(network.ResourceTypeXHR) {
+		return false
+	}
+	requestURL, err := url.Parse(e.URL)
+	if err != nil {
+		return false
+	}
+	documentURL, err := url.Parse(e.DocumentURL)
+	if err != nil {
+		return false
+	}
+	return requestURL.Scheme == documentURL.Scheme && requestURL.Host == documentURL.Host
+}
+
+// Whether the entry is an API request with a JSON response body.
+func (e *NetworkEntry) isAPIResponse() bool {
+	return e.Body != "" && isJSONMIMEType(e.MIMEType) && e.isAPIRequest()
+}
+
+func (e *NetworkEntry) describeAPIResponse() string {
+	return fmt.Sprintf("%s %s (%d, %s)", e.Method, e.URL, e.Status, formatByteSize(int64(len(e.Body))))
+}
+
+// Clears the API responses of the previous action.
+func (r *networkRecorder) beginAction() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.action = nil
+	r.actionCount++
+}
+
+// Waits until the bodies that are being fetched are recorded, or until the timeout.
+func (r *networkRecorder) waitForBodies(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		pending := r.pendingBodies
+		r.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// Waits until the bodies of the API responses of requests that were sent during the current action are recorded,
+// or until the timeout. Requests that were sent before the action, such as long polls, are not waited for.
+func (r *networkRecorder) waitForActionBodies(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for {
+		r.mu.Lock()
+		pending := false
+		for _, entry := range r.action {
+			if entry.bodyPending && entry.action == r.actionCount {
+				pending = true
+				break
+			}
+		}
+		r.mu.Unlock()
+		if !pending || !time.Now().Before(deadline) {
+			return
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// Returns a short description of the API responses that the page loaded during the current action, such as
+// "the page loaded 1 JSON response: GET https://example.com/api/items (200, 1.2 kB); use inspect_network to read
+// it", or "" if there were none.
+func (r *networkRecorder) summarizeAction() string {
+	r.waitForActionBodies(apiResponseBodyTimeout)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	responses := []*NetworkEntry{}
+	for _, entry := range r.action {
+		if entry.isAPIResponse() {
+			responses = append(responses, entry)
+		}
+	}
+	if len(responses) == 0 {
+		return ""
+	}
+	descriptions := []string{}
+	for i, entry := range responses {
+		if i == maxAPIResponseSummaryEntries {
+			descriptions = append(descriptions, fmt.Sprintf("and %d more", len(responses)-i))
+			break
+		}
+		descriptions = append(descriptions, entry.describeAPIResponse())
+	}
+	if len(responses) == 1 {
+		return fmt.Sprintf("the page loaded 1 JSON response: %s; use inspect_network to read it", descriptions[0])
+	}
+	return fmt.Sprintf("the page loaded %d JSON responses: %s; use inspect_network to read them", len(responses), strings.Join(descriptions, "; "))
+}
+
+// Returns the bodies of the JSON responses that pages loaded from their own origin whose urls contain the pattern,
+// newest first. The bodies are trimmed to fit into maxTokens tokens, which defaults to
+// DefaultInspectNetworkMaxTokens; arrays are shortened and long strings are cut before a body is truncated.
+func (b *Browser) InspectNetwork(urlPattern string, maxTokens int) (string, error) {
+	if maxTokens <= 0 {
+		maxTokens = DefaultInspectNetworkMaxTokens
+	} else if maxTokens > MaxInspectNetworkMaxTokens {
+		return "", fmt.Errorf("max_tokens must be at most %d", MaxInspectNetworkMaxTokens)
+	}
+	b.networkLog.waitForBodies(apiResponseBodyTimeout)
+	matches := b.networkLog.findNewestFirst(func(entry *NetworkEntry) bool {
+		return entry.isAPIResponse() && strings.Contains(strings.ToLower(entry.URL), strings.ToLower(urlPattern))
+	})
+	if len(matches) == 0 {
+		if urlPattern == "" {
+			return "no JSON responses were loaded by the pages", nil
+		}
+		return fmt.Sprintf("no JSON responses were loaded from urls that contain \"%s\"", urlPattern), nil
+	}
+	sections := []string{}
+	remaining := maxTokens
+	for i, entry := range matches {
+		header := entry.describeAPIResponse()
+		budget := remaining - llm.ApproxNumTokens(header)
+		if budget <= 0 {
+			sections = append(sections, fmt.Sprintf("%d more matching responses were omitted; use a more specific url_pattern or a larger max_tokens", len(matches)-i))
+			break
+		}
+		body := trimJSON(entry.Body, budget)
+		sections = append(sections, header+"\n"+body)
+		remaining = budget - llm.ApproxNumTokens(body)
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// The shortening steps that are tried in order until a JSON body fits into its budget: the most items that are
+// kept of each array, and the most characters that are kept of each string.
+var jsonTrimSteps = []struct {
+	maxItems       int
+	maxStringChars int
+}{
+	{50, 500},
+	{20, 200},
+	{10, 100},
+	{5, 100},
+	{3, 50},
+	{1, 50},
+}
+
+// Trims a JSON body to at most maxTokens tokens. The body is compacted, then its arrays and strings are shortened
+// with notes of what was left out, and a body that is still too long, or is not valid JSON, is truncated.
+func trimJSON(body string, maxTokens int) string {
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return truncateToTokens(body, maxTokens)
+	}
+	compact := &bytes.Buffer{}
+	if err := json.Compact(compact, []byte(body)); err == nil && llm.ApproxNumTokens(compact.String()) <= maxTokens {
+		return compact.String()
+	}
+	text := compact.String()
+	for _, step := range jsonTrimSteps {
+		data := &bytes.Buffer{}
+		encoder := json.NewEncoder(data)
+		// pages often embed markup in their data, which is easier to read unescaped
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(shortenJSON(value, step.maxItems, step.maxStringChars)); err != nil {
+			break
+		}
+		text = strings.TrimSpace(data.String())
+		if llm.ApproxNumTokens(text) <= maxTokens {
+			return text
+		}
+	}
+	return truncateToTokens(text, maxTokens)
+}
+
+func shortenJSON(value interface{}, maxItems int, maxStringChars int) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		shortened := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			shortened[key] = shortenJSON(item, maxItems, maxStringChars)
+		}
+		return shortened
+	case []interface{}:
+		shortened := []interface{}{}
+		for i, item := range value {
+			if i == maxItems {
+				shortened = append(shortened, fmt.Sprintf("... %d more items", len(value)-i))
+				break
+			}
+			shortened = append(shortened, shortenJSON(item, maxItems, maxStringChars))
+		}
+		return shortened
+	case string:
+		if runes := []rune(value); len(runes) > maxStringChars {
+			return string(runes[:maxStringChars]) + "..."
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func truncateToTokens(text string, maxTokens int) string {
+	if llm.ApproxNumTokens(text) <= maxTokens {
+		return text
+	}
+	// tokens are about four characters long, so the cut starts there and is shortened until the text fits
+	runes := []rune(text)
+	cut := min(len(runes), maxTokens*4)
+	for cut > 0 && llm.ApproxNumTokens(string(runes[:cut])) > maxTokens {
+		cut = cut * 3 / 4
+	}
+	return string(runes[:cut]) + "... (truncated)"
+}
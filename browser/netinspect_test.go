@@ -0,0 +1,91 @@
+package browser
+
+import (
+	"collaborativebrowser/llm"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrimJSON(t *testing.T) {
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"id":%d,"name":"item %d","tags":["a","b","c"]}`, i, i)
+	}
+	longArray := "[" + strings.Join(items, ",") + "]"
+	longString := fmt.Sprintf(`{"user":{"bio":%q,"posts":[{"body":%q}]}}`, strings.Repeat("word ", 2000), strings.Repeat("text ", 2000))
+	tests := []struct {
+		name      string
+		body      string
+		maxTokens int
+		// the exact result, if it is known
+		want string
+		// substrings of the result
+		contains []string
+		// whether the result must be valid JSON
+		valid bool
+	}{
+		{"compacts small json", "{\n  \"a\": [1, 2],\n  \"b\": \"<b>\"\n}", 100, `{"a":[1,2],"b":"<b>"}`, nil, true},
+		{"keeps short invalid json", "not json", 100, "not json", nil, false},
+		{"truncates long invalid json", strings.Repeat("not json ", 1000), 50, "", []string{"... (truncated)"}, false},
+		{"shortens long arrays", longArray, 500, "", []string{`"id":0`, "more items"}, true},
+		{"shortens long strings in nested objects", longString, 300, "", []string{`"bio":"word`, `"body":"text`, `..."`}, true},
+		{"truncates json that cannot be shortened enough", longArray, 5, "", []string{"... (truncated)"}, false},
+	}
+	for _, test := range tests {
+		got := trimJSON(test.body, test.maxTokens)
+		if test.want != "" && got != test.want {
+			t.Errorf("%s: trimJSON = %q, want %q", test.name, got, test.want)
+		}
+		for _, s := range test.contains {
+			if !strings.Contains(got, s) {
+				t.Errorf("%s: trimJSON = %q, want it to contain %q", test.name, got, s)
+			}
+		}
+		if test.valid && !json.Valid([]byte(got)) {
+			t.Errorf("%s: trimJSON = %q, want valid json", test.name, got)
+		}
+		// the truncation note may go beyond the budget by a few tokens
+		if tokens := llm.ApproxNumTokens(strings.TrimSuffix(got, "... (truncated)")); tokens > test.maxTokens {
+			t.Errorf("%s: trimJSON returned %d tokens, want at most %d", test.name, tokens, test.maxTokens)
+		}
+	}
+}
+
+func TestShortenJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          interface{}
+		maxItems       int
+		maxStringChars int
+		want           interface{}
+	}{
+		{"short values", map[string]interface{}{"a": "b", "n": json.Number("1")}, 2, 5, map[string]interface{}{"a": "b", "n": json.Number("1")}},
+		{"long string", "abcdefgh", 2, 3, "abc..."},
+		{"multibyte string", "ééééé", 2, 2, "éé..."},
+		{"long array", []interface{}{"a", "b", "c", "d"}, 2, 5, []interface{}{"a", "b", "... 2 more items"}},
+		{
+			"nested arrays and objects",
+			map[string]interface{}{"rows": []interface{}{
+				[]interface{}{"abcdef", "x", "y"},
+				map[string]interface{}{"tags": []interface{}{"1", "2", "3"}},
+				"z",
+			}},
+			2, 3,
+			map[string]interface{}{"rows": []interface{}{
+				[]interface{}{"abc...", "x", "... 1 more items"},
+				map[string]interface{}{"tags": []interface{}{"1", "2", "... 1 more items"}},
+				"... 1 more items",
+			}},
+		},
+		{"empty array", []interface{}{}, 2, 5, []interface{}{}},
+		{"null", nil, 2, 5, nil},
+	}
+	for _, test := range tests {
+		if got := shortenJSON(test.value, test.maxItems, test.maxStringChars); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: shortenJSON = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
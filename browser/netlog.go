@@ -0,0 +1,347 @@
+package browser
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// A request that a page made and its response, as recorded from CDP network events.
+type NetworkEntry struct {
+	TabID     string
+	RequestID string
+	URL       string
+	Method    string
+	// The type of the resource, such as "Document", "XHR", or "Fetch".
+	ResourceType string
+	// The url of the document that made the request.
+	DocumentURL    string
+	RequestHeaders map[string]string
+	PostData       string
+	StartTime      time.Time
+	// The time from sending the request until its response finished loading or failed.
+	Duration time.Duration
+
+	// The response fields are empty until the response is received.
+	Status          int64
+	StatusText      string
+	MIMEType        string
+	Protocol        string
+	ResponseHeaders map[string]string
+	Timing          *network.ResourceTiming
+	// The url that the request was redirected to, if the response is a redirect.
+	RedirectURL string
+	// The size of the response body after decoding, and the bytes that were transferred including headers.
+	Size        int64
+	EncodedSize int64
+	// The response body, which is only recorded for JSON responses of at most maxNetworkBodySize bytes, and which
+	// is dropped again once it is among the oldest bodies beyond maxNetworkBodiesSize.
+	Body string
+
+	Finished bool
+	// Why the request failed, such as "net::ERR_NAME_NOT_RESOLVED"; empty if it did not fail.
+	ErrorText string
+
+	startTimestamp time.Time
+	// whether the entry was dropped from the log, so that its body is no longer recorded
+	dropped bool
+	// the action during which the request was sent, and whether its body is being fetched
+	action      int
+	bodyPending bool
+}
+
+// Returns a one line summary of the entry, such as "[15:04:05] tab-0 GET 200 application/json 1.2 kB 120ms https://...".
+func (e *NetworkEntry) String() string {
+	status := "pending"
+	switch {
+	case e.ErrorText != "":
+		status = fmt.Sprintf("failed (%s)", e.ErrorText)
+	case e.Status != 0:
+		status = fmt.Sprint(e.Status)
+	}
+	parts := []string{fmt.Sprintf("[%s]", e.StartTime.Format(time.TimeOnly)), e.TabID, e.Method, status}
+	if e.MIMEType != "" {
+		parts = append(parts, e.MIMEType)
+	}
+	if e.Finished {
+		parts = append(parts, formatByteSize(e.EncodedSize), e.Duration.Round(time.Millisecond).String())
+	}
+	return strings.Join(append(parts, e.URL), " ")
+}
+
+func (e *NetworkEntry) clone() *NetworkEntry {
+	clone := *e
+	return &clone
+}
+
+func formatByteSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f kB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+func isJSONMIMEType(mimeType string) bool {
+	return strings.Contains(strings.ToLower(mimeType), "json")
+}
+
+// The most requests that are kept for the session log; older requests are dropped.
+const maxNetworkLogSize = 5000
+
+// The largest JSON response body that is recorded.
+const maxNetworkBodySize = 1 << 20
+
+// The most bytes of response bodies that are kept in total; the bodies of the oldest requests are dropped first.
+const maxNetworkBodiesSize = 32 << 20
+
+// Records the requests of all tabs through CDP network events, for the session log and its HAR export. The API
+// responses of the current action are also collected, so that the agent can read the data that the page loaded.
+type networkRecorder struct {
+	mu  sync.Mutex
+	log []*NetworkEntry
+	// the API responses that finished loading during the current action, which is counted by actionCount
+	action      []*NetworkEntry
+	actionCount int
+	// the number of response bodies that are being fetched
+	pendingBodies int
+	// the total size of the recorded response bodies
+	bodiesSize int
+	// incremented whenever the log changes, so that exports of an unchanged log can be skipped
+	revision int
+	// the entries of the requests that have not finished yet by tab and request id; a redirect reuses the id of
+	// the request that it redirects, so only the entry of the last request of a redirect chain is pending
+	pending map[string]*NetworkEntry
+}
+
+func newNetworkRecorder() *networkRecorder {
+	return &networkRecorder{
+		pending: make(map[string]*NetworkEntry),
+	}
+}
+
+func networkEntryKey(tabID string, requestID network.RequestID) string {
+	return tabID + "/" + string(requestID)
+}
+
+func (r *networkRecorder) handleEvent(t *tab, ev interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		key := networkEntryKey(t.id, ev.RequestID)
+		if previous, ok := r.pending[key]; ok && ev.RedirectResponse != nil {
+			previous.setResponse(ev.RedirectResponse)
+			previous.RedirectURL = ev.Request.URL
+			previous.finish(ev.Timestamp)
+		}
+		entry := &NetworkEntry{
+			TabID:          t.id,
+			RequestID:      string(ev.RequestID),
+			URL:            ev.Request.URL + ev.Request.URLFragment,
+			Method:         ev.Request.Method,
+			ResourceType:   string(ev.Type),
+			DocumentURL:    ev.DocumentURL,
+			RequestHeaders: headersToMap(ev.Request.Headers),
+			PostData:       ev.Request.PostData,
+			StartTime:      time.Now(),
+			action:         r.actionCount,
+		}
+		if ev.WallTime != nil {
+			entry.StartTime = ev.WallTime.Time()
+		}
+		if ev.Timestamp != nil {
+			entry.startTimestamp = ev.Timestamp.Time()
+		}
+		r.pending[key] = entry
+		r.append(entry)
+		r.revision++
+	case *network.EventResponseReceived:
+		if entry, ok := r.pending[networkEntryKey(t.id, ev.RequestID)]; ok {
+			entry.setResponse(ev.Response)
+			r.revision++
+		}
+	case *network.EventDataReceived:
+		if entry, ok := r.pending[networkEntryKey(t.id, ev.RequestID)]; ok {
+			entry.Size += ev.DataLength
+			r.revision++
+		}
+	case *network.EventLoadingFinished:
+		key := networkEntryKey(t.id, ev.RequestID)
+		entry, ok := r.pending[key]
+		if !ok {
+			return
+		}
+		delete(r.pending, key)
+		entry.EncodedSize = int64(ev.EncodedDataLength)
+		entry.finish(ev.Timestamp)
+		r.revision++
+		if isJSONMIMEType(entry.MIMEType) && entry.Size <= maxNetworkBodySize {
+			if entry.isAPIRequest() {
+				r.action = append(r.action, entry)
+			}
+			// event handlers must not block, so the body is fetched in a separate goroutine
+			r.pendingBodies++
+			entry.bodyPending = true
+			go r.recordBody(t, entry, ev.RequestID)
+		}
+	case *network.EventLoadingFailed:
+		key := networkEntryKey(t.id, ev.RequestID)
+		entry, ok := r.pending[key]
+		if !ok {
+			return
+		}
+		delete(r.pending, key)
+		entry.ErrorText = ev.ErrorText
+		if entry.ErrorText == "" && ev.Canceled {
+			entry.ErrorText = "canceled"
+		}
+		entry.finish(ev.Timestamp)
+		r.revision++
+	}
+}
+
+func (e *NetworkEntry) setResponse(response *network.Response) {
+	e.Status = response.Status
+	e.StatusText = response.StatusText
+	e.MIMEType = response.MimeType
+	e.Protocol = response.Protocol
+	e.ResponseHeaders = headersToMap(response.Headers)
+	e.Timing = response.Timing
+	e.EncodedSize = int64(response.EncodedDataLength)
+}
+
+func (e *NetworkEntry) finish(timestamp *cdp.MonotonicTime) {
+	e.Finished = true
+	if timestamp != nil && !e.startTimestamp.IsZero() {
+		e.Duration = timestamp.Time().Sub(e.startTimestamp)
+	}
+}
+
+func (r *networkRecorder) append(entry *NetworkEntry) {
+	r.log = append(r.log, entry)
+	if len(r.log) <= maxNetworkLogSize {
+		return
+	}
+	for _, dropped := range r.log[:len(r.log)-maxNetworkLogSize] {
+		key := networkEntryKey(dropped.TabID, network.RequestID(dropped.RequestID))
+		if r.pending[key] == dropped {
+			delete(r.pending, key)
+		}
+		r.bodiesSize -= len(dropped.Body)
+		dropped.dropped = true
+	}
+	r.log = r.log[len(r.log)-maxNetworkLogSize:]
+}
+
+func (r *networkRecorder) recordBody(t *tab, entry *NetworkEntry, requestID network.RequestID) {
+	c := chromedp.FromContext(t.ctx)
+	body, err := network.GetResponseBody(requestID).Do(cdp.WithExecutor(t.ctx, c.Target))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pendingBodies--
+	entry.bodyPending = false
+	if err != nil {
+		log.Printf("error getting response body of %s: %v", entry.URL, err)
+		return
+	}
+	if len(body) <= maxNetworkBodySize && !entry.dropped {
+		entry.Body = string(body)
+		r.bodiesSize += len(entry.Body)
+		r.dropOldBodies()
+		r.revision++
+	}
+}
+
+// Drops the bodies of the oldest entries until the recorded bodies fit into maxNetworkBodiesSize.
+func (r *networkRecorder) dropOldBodies() {
+	for _, entry := range r.log {
+		if r.bodiesSize <= maxNetworkBodiesSize {
+			return
+		}
+		r.bodiesSize -= len(entry.Body)
+		entry.Body = ""
+	}
+}
+
+// Converts CDP headers to a map of names to values. Headers that were sent more than once are joined by newlines.
+func headersToMap(headers network.Headers) map[string]string {
+	m := make(map[string]string, len(headers))
+	for name, value := range headers {
+		m[name] = fmt.Sprint(value)
+	}
+	return m
+}
+
+func sortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the requests of all tabs of the session, oldest first.
+func (b *Browser) GetNetworkLog() []*NetworkEntry {
+	return b.GetLastNetworkEntries(0)
+}
+
+// Returns the last n requests of all tabs, oldest first, or all requests if n is not positive. Only the last 5000
+// requests are kept, and the response bodies of the oldest requests are dropped once they add up to 32 MB.
+func (b *Browser) GetLastNetworkEntries(n int) []*NetworkEntry {
+	b.networkLog.mu.Lock()
+	defer b.networkLog.mu.Unlock()
+	entries := b.networkLog.log
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	clones := make([]*NetworkEntry, len(entries))
+	for i, entry := range entries {
+		clones[i] = entry.clone()
+	}
+	return clones
+}
+
+// Returns a number that changes whenever a request is added to the network log or an entry of it is updated.
+func (b *Browser) GetNetworkLogRevision() int {
+	b.networkLog.mu.Lock()
+	defer b.networkLog.mu.Unlock()
+	return b.networkLog.revision
+}
+
+// Returns the MIME type of the newest recorded response to the url, ignoring fragments, or "" if there is none.
+func (r *networkRecorder) responseMIMEType(rawURL string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rawURL, _, _ = strings.Cut(rawURL, "#")
+	for i := len(r.log) - 1; i >= 0; i-- {
+		if entryURL, _, _ := strings.Cut(r.log[i].URL, "#"); entryURL == rawURL && r.log[i].MIMEType != "" {
+			return r.log[i].MIMEType
+		}
+	}
+	return ""
+}
+
+// Returns copies of the entries for which match returns true, newest first.
+func (r *networkRecorder) findNewestFirst(match func(*NetworkEntry) bool) []*NetworkEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matches := []*NetworkEntry{}
+	for i := len(r.log) - 1; i >= 0; i-- {
+		if match(r.log[i]) {
+			matches = append(matches, r.log[i].clone())
+		}
+	}
+	return matches
+}
@@ -0,0 +1,291 @@
+package browser
+
+import (
+	"collaborativebrowser/trajectory"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// The number of mouse moves between the source and the target of a drag, since pages often only start a drag
+	// after the mouse has moved a few pixels with the button pressed.
+	dragSteps = 10
+	// How long a drag waits for the page to start a native drag after the last mouse move.
+	dragInterceptTimeout = 100 * time.Millisecond
+)
+
+// The position of an element for the mouse, in css pixels from the top left corner of the viewport.
+type elementPosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	// whether the element has a size; if not, the position is meaningless
+	Visible bool `json:"visible"`
+	// whether the element, or one of its descendants, is the topmost element at its center, so that the mouse
+	// reaches it rather than an overlay or a sticky header
+	Hittable bool `json:"hittable"`
+}
+
+// Scrolls an element into view and returns the position of its center. block is how the element is aligned, such
+// as "center", or "nearest" to only scroll if the element is not in view already.
+func (b *Browser) locateElementByQuerySelector(query string, block string) (*elementPosition, error) {
+	js := deepQueryJS + fmt.Sprintf(`function hitTest(element, x, y) {
+	const root = element.getRootNode();
+	const hit = (root.elementFromPoint ? root : element.ownerDocument).elementFromPoint(x, y);
+	return hit !== null && (hit === element || element.contains(hit));
+}
+function locateElementByQuerySelector(query, block) {
+	const element = deepQuerySelector(query);
+	if (!element) {
+		throw new Error("element not found");
+	}
+	element.scrollIntoView({block: block, inline: block});
+	const rect = element.getBoundingClientRect();
+	if (rect.width === 0 || rect.height === 0) {
+		return {x: 0, y: 0, visible: false, hittable: false};
+	}
+	let x = rect.left + rect.width / 2;
+	let y = rect.top + rect.height / 2;
+	let hittable = hitTest(element, x, y);
+	// the coordinates are relative to the frame of the element, so the offsets of the enclosing frames are added
+	for (let frame = element.ownerDocument.defaultView.frameElement; frame; frame = frame.ownerDocument.defaultView.frameElement) {
+		const frameRect = frame.getBoundingClientRect();
+		x += frameRect.left + frame.clientLeft;
+		y += frameRect.top + frame.clientTop;
+		hittable = hittable && hitTest(frame, x, y);
+	}
+	hittable = hittable && x >= 0 && y >= 0 && x < window.innerWidth && y < window.innerHeight;
+	return {x, y, visible: true, hittable};
+}
+locateElementByQuerySelector(%s, %s);`, quoteJS(query), quoteJS(block))
+	var position elementPosition
+	if err := b.run(chromedp.Evaluate(js, &position)); err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+func mouseMove(x, y float64, buttons int64) chromedp.Action {
+	params := input.DispatchMouseEvent(input.MouseMoved, x, y).WithButtons(buttons)
+	if buttons != 0 {
+		params = params.WithButton(input.Left)
+	}
+	return params
+}
+
+func mousePress(x, y float64) chromedp.Action {
+	return input.DispatchMouseEvent(input.MousePressed, x, y).WithButton(input.Left).WithButtons(1).WithClickCount(1)
+}
+
+func mouseRelease(x, y float64) chromedp.Action {
+	return input.DispatchMouseEvent(input.MouseReleased, x, y).WithButton(input.Left).WithButtons(0).WithClickCount(1)
+}
+
+// Clicks a point of the viewport with the left mouse button.
+func (b *Browser) MouseClickXY(x, y float64) error {
+	return b.run(mouseMove(x, y, 0), mousePress(x, y), mouseRelease(x, y))
+}
+
+// Clicks an element with the mouse at its center so that the page receives the same events as a user click.
+// Elements that have no size or that are covered at their center are clicked through the DOM instead.
+func (b *Browser) MouseClickByQuerySelector(query string) error {
+	position, err := b.locateElementByQuerySelector(query, "center")
+	if err != nil {
+		return fmt.Errorf("error locating element: %w", err)
+	} else if !position.Visible || !position.Hittable {
+		log.Printf("element %s cannot be reached by the mouse; clicking it through the dom", query)
+		return b.ClickByQuerySelector(query)
+	}
+	return b.MouseClickXY(position.X, position.Y)
+}
+
+func (b *Browser) MouseClickByVirtualID(virtualID string) error {
+	return b.MouseClickByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID))
+}
+
+// Moves the mouse over the center of an element.
+func (b *Browser) HoverByQuerySelector(query string) error {
+	position, err := b.locateElementByQuerySelector(query, "center")
+	if err != nil {
+		return fmt.Errorf("error locating element: %w", err)
+	} else if !position.Visible {
+		return errors.New("element is not visible")
+	}
+	return b.run(mouseMove(position.X, position.Y, 0))
+}
+
+func (b *Browser) HoverByVirtualID(virtualID string) error {
+	return b.HoverByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID))
+}
+
+// Drags an element onto another element with the mouse. Pages that handle pointer events see the mouse moves
+// directly; if the page starts a native drag instead, it is intercepted and the drag events are dispatched to the
+// target, since a headless browser does not perform native drags itself.
+func (b *Browser) DragByQuerySelectors(fromQuery string, toQuery string) error {
+	if _, err := b.locateElementByQuerySelector(fromQuery, "center"); err != nil {
+		return fmt.Errorf("error locating element to drag: %w", err)
+	}
+	to, err := b.locateElementByQuerySelector(toQuery, "nearest")
+	if err != nil {
+		return fmt.Errorf("error locating element to drop onto: %w", err)
+	} else if !to.Visible {
+		return errors.New("element to drop onto is not visible")
+	}
+	// bringing the target into view may have scrolled the source, so it is located again
+	from, err := b.locateElementByQuerySelector(fromQuery, "nearest")
+	if err != nil {
+		return fmt.Errorf("error locating element to drag: %w", err)
+	} else if !from.Visible {
+		return errors.New("element to drag is not visible")
+	}
+
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	intercepted := make(chan *input.DragData, 1)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*input.EventDragIntercepted); ok {
+			select {
+			case intercepted <- ev.Data:
+			default:
+			}
+		}
+	})
+	if err := b.run(input.SetInterceptDrags(true)); err != nil {
+		return fmt.Errorf("error intercepting drags: %w", err)
+	}
+	defer func() {
+		if err := b.run(input.SetInterceptDrags(false)); err != nil {
+			log.Println("error restoring drags:", err)
+		}
+	}()
+
+	if err := b.run(mouseMove(from.X, from.Y, 0), mousePress(from.X, from.Y)); err != nil {
+		return fmt.Errorf("error pressing mouse: %w", err)
+	}
+	var data *input.DragData
+	for i := 1; i <= dragSteps && data == nil; i++ {
+		x := from.X + (to.X-from.X)*float64(i)/dragSteps
+		y := from.Y + (to.Y-from.Y)*float64(i)/dragSteps
+		if err := b.run(mouseMove(x, y, 1)); err != nil {
+			return fmt.Errorf("error moving mouse: %w", err)
+		}
+		select {
+		case data = <-intercepted:
+		default:
+		}
+	}
+	if data == nil {
+		select {
+		case data = <-intercepted:
+		case <-time.After(dragInterceptTimeout):
+		}
+	}
+	if data != nil {
+		if err := b.run(
+			input.DispatchDragEvent(input.DragEnter, to.X, to.Y, data),
+			input.DispatchDragEvent(input.DragOver, to.X, to.Y, data),
+			input.DispatchDragEvent(input.Drop, to.X, to.Y, data),
+		); err != nil {
+			return fmt.Errorf("error dropping: %w", err)
+		}
+	}
+	if err := b.run(mouseRelease(to.X, to.Y)); err != nil {
+		return fmt.Errorf("error releasing mouse: %w", err)
+	}
+	return nil
+}
+
+func (b *Browser) DragByVirtualIDs(fromVirtualID string, toVirtualID string) error {
+	return b.DragByQuerySelectors(fmt.Sprintf("[data-vid=\"%s\"]", fromVirtualID), fmt.Sprintf("[data-vid=\"%s\"]", toVirtualID))
+}
+
+// Returns a short description of the element at a point of the viewport, such as "button \"Submit\" (id=3)", or ""
+// if there is none. Open shadow roots and same-origin iframes at the point are searched for the innermost element.
+func (b *Browser) describeElementAtPoint(x, y float64) (string, error) {
+	js := fmt.Sprintf(`function describeElementAtPoint(x, y) {
+	let element = document.elementFromPoint(x, y);
+	while (element) {
+		if (element.shadowRoot) {
+			const inner = element.shadowRoot.elementFromPoint(x, y);
+			if (inner && inner !== element) {
+				element = inner;
+				continue;
+			}
+		}
+		if (element.tagName === 'IFRAME' || element.tagName === 'FRAME') {
+			let contentDocument = null;
+			try {
+				contentDocument = element.contentDocument;
+			} catch (e) {}
+			if (contentDocument) {
+				const rect = element.getBoundingClientRect();
+				x -= rect.left + element.clientLeft;
+				y -= rect.top + element.clientTop;
+				const inner = contentDocument.elementFromPoint(x, y);
+				if (inner) {
+					element = inner;
+					continue;
+				}
+			}
+		}
+		break;
+	}
+	if (!element) {
+		return '';
+	}
+	const target = element.closest('[data-vid]') || element;
+	let description = target.tagName.toLowerCase();
+	const text = (target.getAttribute('aria-label') || target.innerText || target.value || '').trim().replace(/\s+/g, ' ');
+	if (text) {
+		description += ' "' + (text.length > 50 ? text.slice(0, 50) + '...' : text) + '"';
+	}
+	if (target.hasAttribute('data-vid')) {
+		description += ' (id=' + target.getAttribute('data-vid') + ')';
+	}
+	return description;
+}
+describeElementAtPoint(%g, %g);`, x, y)
+	var description string
+	if err := b.run(chromedp.Evaluate(js, &description)); err != nil {
+		return "", err
+	}
+	return description, nil
+}
+
+func (b *Browser) getViewportSize() (width float64, height float64, err error) {
+	var size []float64
+	if err := b.run(chromedp.Evaluate(`[window.innerWidth, window.innerHeight];`, &size)); err != nil {
+		return 0, 0, err
+	} else if len(size) != 2 {
+		return 0, 0, fmt.Errorf("unexpected viewport size: %v", size)
+	}
+	return size[0], size[1], nil
+}
+
+// Clicks a point of the viewport, in css pixels from its top left corner, for elements that have no virtual id.
+// Returns a description of the element that was clicked.
+func (b *Browser) ClickAt(x, y float64) (string, error) {
+	previousLocation := b.display.Location
+	width, height, err := b.getViewportSize()
+	if err != nil {
+		return "", fmt.Errorf("error getting viewport size: %w", err)
+	} else if x < 0 || y < 0 || x >= width || y >= height {
+		return "", fmt.Errorf("point (%g, %g) is outside of the viewport of %gx%g", x, y, width, height)
+	}
+	description, err := b.describeElementAtPoint(x, y)
+	if err != nil {
+		log.Println("error describing element at point:", err)
+	}
+	if err := b.MouseClickXY(x, y); err != nil {
+		return "", fmt.Errorf("error clicking at point: %w", err)
+	}
+	if err := b.afterClick(trajectory.BrowserActionTypeClickAt, previousLocation); err != nil {
+		return "", err
+	}
+	return description, nil
+}
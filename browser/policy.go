@@ -0,0 +1,186 @@
+package browser
+
+import (
+	"collaborativebrowser/trajectory"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Restricts the pages that the browser may visit. Documents that are not permitted are failed before they load into
+// the main frame of a tab, and pages that reach a blocked url anyway are left after they load. Domain patterns are either a domain, which also matches its
+// subdomains, or a pattern with "*" wildcards such as "*.example.com". Domain patterns only apply to urls with
+// a host; urls without one, such as file: and data: urls, are restricted by their scheme.
+type NavigationPolicy struct {
+	// If set, only urls on these domains may be visited.
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+	// Urls on these domains may not be visited, even if they are allowed.
+	DeniedDomains []string `json:"deniedDomains,omitempty"`
+	// If set, only urls with these schemes, such as "https", may be visited.
+	AllowedSchemes []string `json:"allowedSchemes,omitempty"`
+	// Urls with these schemes may not be visited, even if they are allowed.
+	DeniedSchemes []string `json:"deniedSchemes,omitempty"`
+}
+
+// Returned when a navigation is not permitted by the navigation policy of the browser.
+var ErrNavigationBlocked = errors.New("navigation blocked by the browser policy")
+
+type NavigationBlockedError struct {
+	URL    string
+	Reason string
+}
+
+func (e *NavigationBlockedError) Error() string {
+	return fmt.Sprintf("navigation to %s was blocked: %s", e.URL, e.Reason)
+}
+
+func (e *NavigationBlockedError) Unwrap() error {
+	return ErrNavigationBlocked
+}
+
+// Returns a *NavigationBlockedError if the url may not be visited. A nil policy permits every url.
+func (p *NavigationPolicy) Check(rawURL string) error {
+	if p == nil || isInternalPage(rawURL) {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &NavigationBlockedError{URL: rawURL, Reason: fmt.Sprintf("the url cannot be parsed: %v", err)}
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if containsFold(p.DeniedSchemes, scheme) {
+		return &NavigationBlockedError{URL: rawURL, Reason: fmt.Sprintf("the %s scheme is denied", scheme)}
+	} else if len(p.AllowedSchemes) > 0 && !containsFold(p.AllowedSchemes, scheme) {
+		return &NavigationBlockedError{URL: rawURL, Reason: fmt.Sprintf("the %s scheme is not allowed; allowed schemes are %s", scheme, strings.Join(p.AllowedSchemes, ", "))}
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return nil
+	}
+	for _, pattern := range p.DeniedDomains {
+		if matchesDomainPattern(host, pattern) {
+			return &NavigationBlockedError{URL: rawURL, Reason: fmt.Sprintf("%s is a denied domain", host)}
+		}
+	}
+	if len(p.AllowedDomains) == 0 {
+		return nil
+	}
+	for _, pattern := range p.AllowedDomains {
+		if matchesDomainPattern(host, pattern) {
+			return nil
+		}
+	}
+	return &NavigationBlockedError{URL: rawURL, Reason: fmt.Sprintf("%s is not an allowed domain; allowed domains are %s", host, strings.Join(p.AllowedDomains, ", "))}
+}
+
+// Reports whether the url is a page of the browser itself, such as the blank page or an error page, which is
+// always permitted so that the browser can leave blocked pages.
+func isInternalPage(rawURL string) bool {
+	return rawURL == "" || rawURL == "about:blank" || strings.HasPrefix(rawURL, "chrome-error://")
+}
+
+func matchesDomainPattern(host string, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	} else if !strings.Contains(pattern, "*") {
+		return matchesDomain(host, pattern)
+	}
+	matched, err := path.Match(pattern, host)
+	return err == nil && matched
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSuffix(strings.TrimSpace(v), ":"), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Browser) GetNavigationPolicy() *NavigationPolicy {
+	return b.navigationPolicy
+}
+
+func (b *Browser) checkNavigation(URL string) error {
+	return b.navigationPolicy.Check(URL)
+}
+
+// Checks the location of the active tab against the navigation policy and leaves the page if it is blocked.
+// Navigations that were failed by request interception are reported as well, and the error page that the tab
+// shows in their place is left. Returns the *NavigationBlockedError of the last blocked navigation, if any.
+func (b *Browser) checkLocation() error {
+	if err := b.checkNavigation(b.display.Location); err != nil {
+		if leaveErr := b.leaveBlockedPage(); leaveErr != nil {
+			return leaveErr
+		}
+		return err
+	}
+	blocked := b.interceptor.takeBlockedNavigations(b.activeTab)
+	if len(blocked) == 0 {
+		return nil
+	}
+	b.blockedNavigations = append(b.blockedNavigations, blocked[:len(blocked)-1]...)
+	if strings.HasPrefix(b.display.Location, "chrome-error://") {
+		if err := b.leaveBlockedPage(); err != nil {
+			return err
+		}
+	}
+	return blocked[len(blocked)-1]
+}
+
+// Leaves the page of the active tab after it navigated to a blocked url by going back in its history, or to
+// the blank page if the previous page is not available or is also blocked.
+func (b *Browser) leaveBlockedPage() error {
+	if err := b.run(chromedp.NavigateBack()); err == nil {
+		b.waitForPageToSettle(trajectory.BrowserActionTypeGoBack)
+		if location, err := b.getLocation(); err == nil && b.checkNavigation(location) == nil {
+			return b.updateDisplay()
+		}
+	}
+	if err := b.run(chromedp.Navigate("about:blank")); err != nil {
+		return fmt.Errorf("error leaving blocked page: %w", err)
+	}
+	return b.updateDisplay()
+}
+
+// Closes the newly attached tabs that are at a blocked url, whether a page opened them or not. The blocked
+// navigations are recorded so that they can be reported with the result of the action that opened them.
+func (b *Browser) closeBlockedTabs(discovered []*tab) []*tab {
+	allowed := []*tab{}
+	for _, t := range discovered {
+		err := b.checkNavigation(t.location)
+		if err == nil {
+			allowed = append(allowed, t)
+			continue
+		}
+		if err := chromedp.Run(t.ctx, page.Close()); err != nil {
+			log.Printf("error closing blocked tab %s: %v", t.id, err)
+		}
+		t.cancel()
+		b.removeTab(t)
+		var blocked *NavigationBlockedError
+		if errors.As(err, &blocked) {
+			b.blockedNavigations = append(b.blockedNavigations, blocked)
+		}
+	}
+	return allowed
+}
+
+// Returns and clears the navigations that were blocked outside of the action that was taken, including those that
+// were failed by request interception in tabs other than the active tab.
+func (b *Browser) takeBlockedNavigations() []*NavigationBlockedError {
+	blocked := b.blockedNavigations
+	b.blockedNavigations = nil
+	for _, t := range b.tabs {
+		blocked = append(blocked, b.interceptor.takeBlockedNavigations(t)...)
+	}
+	return blocked
+}
@@ -0,0 +1,124 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestNavigationPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *NavigationPolicy
+		url     string
+		blocked bool
+	}{
+		{"nil policy", nil, "https://example.com", false},
+		{"empty policy", &NavigationPolicy{}, "https://example.com", false},
+		{"allowed domain", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "https://example.com/path", false},
+		{"allowed subdomain", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "https://www.example.com", false},
+		{"allowed domain is case insensitive", &NavigationPolicy{AllowedDomains: []string{"Example.COM"}}, "https://EXAMPLE.com", false},
+		{"domain with allowed suffix", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "https://notexample.com", true},
+		{"other domain", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "https://example.org", true},
+		{"allowed domain in query", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "https://evil.com/?example.com", true},
+		{"allowed domain as userinfo", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "https://example.com@evil.com", true},
+		{"wildcard matches subdomain", &NavigationPolicy{AllowedDomains: []string{"*.example.com"}}, "https://a.example.com", false},
+		{"wildcard does not match domain", &NavigationPolicy{AllowedDomains: []string{"*.example.com"}}, "https://example.com", true},
+		{"denied domain", &NavigationPolicy{DeniedDomains: []string{"evil.com"}}, "https://evil.com", true},
+		{"denied subdomain", &NavigationPolicy{DeniedDomains: []string{"evil.com"}}, "https://www.evil.com", true},
+		{"denied overrides allowed", &NavigationPolicy{AllowedDomains: []string{"example.com"}, DeniedDomains: []string{"admin.example.com"}}, "https://admin.example.com", true},
+		{"allowed sibling of denied", &NavigationPolicy{AllowedDomains: []string{"example.com"}, DeniedDomains: []string{"admin.example.com"}}, "https://www.example.com", false},
+		{"domain with port", &NavigationPolicy{AllowedDomains: []string{"localhost"}}, "http://localhost:8080/", false},
+		{"allowed scheme", &NavigationPolicy{AllowedSchemes: []string{"https"}}, "https://example.com", false},
+		{"not allowed scheme", &NavigationPolicy{AllowedSchemes: []string{"https"}}, "http://example.com", true},
+		{"allowed scheme with colon", &NavigationPolicy{AllowedSchemes: []string{"https:"}}, "https://example.com", false},
+		{"denied scheme", &NavigationPolicy{DeniedSchemes: []string{"file"}}, "file:///etc/passwd", true},
+		{"denied scheme is case insensitive", &NavigationPolicy{DeniedSchemes: []string{"FILE"}}, "File:///etc/passwd", true},
+		{"url without host ignores domains", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "data:text/html,hi", false},
+		{"blank page is always permitted", &NavigationPolicy{AllowedSchemes: []string{"https"}}, "about:blank", false},
+		{"error page is always permitted", &NavigationPolicy{AllowedDomains: []string{"example.com"}}, "chrome-error://chromewebdata/", false},
+		{"unparsable url", &NavigationPolicy{}, "http://[::1", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Check(test.url)
+			if blocked := err != nil; blocked != test.blocked {
+				t.Fatalf("Check(%q) = %v, want blocked=%t", test.url, err, test.blocked)
+			}
+			if err == nil {
+				return
+			}
+			var blockedErr *NavigationBlockedError
+			if !errors.As(err, &blockedErr) || !errors.Is(err, ErrNavigationBlocked) {
+				t.Errorf("Check(%q) = %#v, want a *NavigationBlockedError that wraps ErrNavigationBlocked", test.url, err)
+			}
+		})
+	}
+}
+
+func TestMatchesDomainPattern(t *testing.T) {
+	tests := []struct {
+		host    string
+		pattern string
+		want    bool
+	}{
+		{"example.com", "example.com", true},
+		{"www.example.com", "example.com", true},
+		{"example.com", " Example.com ", true},
+		{"notexample.com", "example.com", false},
+		{"example.com.evil.com", "example.com", false},
+		{"a.example.com", "*.example.com", true},
+		{"a.b.example.com", "*.example.com", true},
+		{"example.com", "*.example.com", false},
+		{"example.org", "example.*", true},
+		{"example.com", "", false},
+		{"example.com", "[", false},
+	}
+	for _, test := range tests {
+		if got := matchesDomainPattern(test.host, test.pattern); got != test.want {
+			t.Errorf("matchesDomainPattern(%q, %q) = %t, want %t", test.host, test.pattern, got, test.want)
+		}
+	}
+}
+
+func TestRequestInterceptorCheckNavigation(t *testing.T) {
+	interceptor, err := newRequestInterceptor(&LaunchConfig{}, &NavigationPolicy{AllowedDomains: []string{"example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paused := func(frameID cdp.FrameID, resourceType network.ResourceType, url string) *fetch.EventRequestPaused {
+		return &fetch.EventRequestPaused{FrameID: frameID, ResourceType: resourceType, Request: &network.Request{URL: url}}
+	}
+	tests := []struct {
+		name    string
+		paused  *fetch.EventRequestPaused
+		blocked bool
+	}{
+		{"allowed main frame document", paused("target", network.ResourceTypeDocument, "https://example.com/"), false},
+		{"blocked main frame document", paused("target", network.ResourceTypeDocument, "https://example.org/"), true},
+		{"blocked child frame document", paused("child", network.ResourceTypeDocument, "https://example.org/"), false},
+		{"blocked subresource", paused("target", network.ResourceTypeScript, "https://example.org/app.js"), false},
+		{"blank page", paused("target", network.ResourceTypeDocument, "about:blank"), false},
+	}
+	for _, test := range tests {
+		err := interceptor.checkNavigation("target", test.paused)
+		if blocked := err != nil; blocked != test.blocked {
+			t.Errorf("%s: checkNavigation = %v, want blocked=%t", test.name, err, test.blocked)
+		}
+	}
+}
+
+func TestNewRequestInterceptorForNavigationPolicy(t *testing.T) {
+	if interceptor, err := newRequestInterceptor(&LaunchConfig{}, nil); err != nil || interceptor != nil {
+		t.Errorf("newRequestInterceptor without a policy = %v, %v, want nil", interceptor, err)
+	}
+	interceptor, err := newRequestInterceptor(&LaunchConfig{}, &NavigationPolicy{})
+	if err != nil || interceptor == nil {
+		t.Fatalf("newRequestInterceptor with a policy = %v, %v, want an interceptor", interceptor, err)
+	} else if !interceptor.onlyChecksNavigations() {
+		t.Errorf("an interceptor for a policy should only check navigations")
+	}
+}
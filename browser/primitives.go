@@ -1,15 +1,22 @@
 package browser
 
 import (
+	"collaborativebrowser/browser/virtualid"
+	"collaborativebrowser/translators/html2md"
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
 )
 
 func (b *Browser) DoesVirtualIDExist(virtualID string) (bool, error) {
 	var exists bool
-	if err := b.run(chromedp.Evaluate(fmt.Sprintf("document.querySelector('[data-vid=\"%s\"]') !== null", virtualID), &exists)); err != nil {
+	if err := b.run(chromedp.Evaluate(deepQueryJS+fmt.Sprintf("deepQuerySelector('[data-vid=\"%s\"]') !== null;", virtualID), &exists)); err != nil {
 		return false, err
 	} else {
 		return exists, nil
@@ -17,8 +24,8 @@ func (b *Browser) DoesVirtualIDExist(virtualID string) (bool, error) {
 }
 
 func (b *Browser) ClickByQuerySelector(query string) error {
-	js := fmt.Sprintf(`function clickByQuerySelector(query) {
-	const element = document.querySelector(query);
+	js := deepQueryJS + fmt.Sprintf(`function clickByQuerySelector(query) {
+	const element = deepQuerySelector(query);
 	if (element) {
 		element.click();
 	} else {
@@ -33,38 +40,353 @@ func (b *Browser) ClickByVirtualID(virtualID string) error {
 	return b.ClickByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID))
 }
 
-func (b *Browser) SendTextByQuerySelector(query string, text string) error {
-	js := fmt.Sprintf(`function sendTextToSelector(selector, text) {
-	try {
-		var element = document.querySelector(selector);
-		if (!element) {
-			throw new Error('Element not found');
+// Focuses an element and optionally selects its contents. Returns whether the element has any content.
+func (b *Browser) FocusByQuerySelector(query string, selectContents bool) (bool, error) {
+	js := deepQueryJS + fmt.Sprintf(`function focusByQuerySelector(query, selectContents) {
+	const element = deepQuerySelector(query);
+	if (!element) {
+		throw new Error("element not found");
+	}
+	element.scrollIntoView({block: 'center', inline: 'nearest'});
+	for (let frame = element.ownerDocument.defaultView.frameElement; frame; frame = frame.ownerDocument.defaultView.frameElement) {
+		frame.focus();
+	}
+	element.focus();
+	if (element.tagName === 'INPUT' || element.tagName === 'TEXTAREA') {
+		if (selectContents) {
+			try {
+				element.setSelectionRange(0, element.value.length);
+			} catch (e) {
+				element.select();
+			}
+		} else {
+			try {
+				element.setSelectionRange(element.value.length, element.value.length);
+			} catch (e) {}
+		}
+		return element.value.length > 0;
+	} else if (element.isContentEditable) {
+		const range = element.ownerDocument.createRange();
+		range.selectNodeContents(element);
+		if (!selectContents) {
+			range.collapse(false);
+		}
+		const selection = element.ownerDocument.getSelection();
+		selection.removeAllRanges();
+		selection.addRange(range);
+		return element.textContent.length > 0;
+	}
+	return false;
+}
+focusByQuerySelector('%s', %t);`, query, selectContents)
+	var hasContent bool
+	if err := b.run(chromedp.Evaluate(js, &hasContent)); err != nil {
+		return false, err
+	} else {
+		return hasContent, nil
+	}
+}
+
+// Types text into an element with real key events. If clear is true, the existing content is deleted first.
+func (b *Browser) SendTextByQuerySelector(query string, text string, clear bool) error {
+	if hasContent, err := b.FocusByQuerySelector(query, clear); err != nil {
+		return fmt.Errorf("error focusing element: %w", err)
+	} else if clear && hasContent {
+		if err := b.run(chromedp.KeyEvent(kb.Backspace)); err != nil {
+			return fmt.Errorf("error clearing element: %w", err)
+		}
+	}
+	return b.run(chromedp.KeyEvent(text))
+}
+
+func (b *Browser) SendTextByVirtualID(virtualID string, text string, clear bool) error {
+	return b.SendTextByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID), text, clear)
+}
+
+// Presses a key combination such as "Enter" or "Shift+Tab" on the focused element.
+func (b *Browser) PressKeyCombination(combination string) error {
+	key, modifiers, err := ParseKeyCombination(combination)
+	if err != nil {
+		return err
+	}
+	return b.run(chromedp.KeyEvent(key, chromedp.KeyModifiers(modifiers...)))
+}
+
+func (b *Browser) PressKeyCombinationByQuerySelector(query string, combination string) error {
+	if _, err := b.FocusByQuerySelector(query, false); err != nil {
+		return fmt.Errorf("error focusing element: %w", err)
+	}
+	return b.PressKeyCombination(combination)
+}
+
+func (b *Browser) PressKeyCombinationByVirtualID(virtualID string, combination string) error {
+	return b.PressKeyCombinationByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID), combination)
+}
+
+// Selects an option by value or by visible label and dispatches the events that a user selection would.
+func (b *Browser) SelectOptionByQuerySelector(query string, option string) (string, error) {
+	js := deepQueryJS + fmt.Sprintf(`function selectOptionByQuerySelector(query, option) {
+	const element = deepQuerySelector(query);
+	if (!element) {
+		throw new Error("element not found");
+	} else if (element.tagName !== 'SELECT') {
+		throw new Error("element is not a select");
+	}
+	const options = Array.from(element.options);
+	const normalize = text => text.trim().toLowerCase();
+	const match = options.find(o => o.value === option)
+		|| options.find(o => o.text.trim() === option)
+		|| options.find(o => normalize(o.text) === normalize(option));
+	if (!match) {
+		throw new Error('option not found; available options: ' + options.map(o => JSON.stringify(o.text.trim())).join(', '));
+	} else if (match.disabled) {
+		throw new Error('option is disabled: ' + JSON.stringify(match.text.trim()));
+	}
+	element.selectedIndex = match.index;
+	element.dispatchEvent(new Event('input', {bubbles: true}));
+	element.dispatchEvent(new Event('change', {bubbles: true}));
+	return match.text.trim();
+}
+selectOptionByQuerySelector('%s', %s);`, query, quoteJS(option))
+	var selected string
+	if err := b.run(chromedp.Evaluate(js, &selected)); err != nil {
+		return "", err
+	} else {
+		return selected, nil
+	}
+}
+
+func (b *Browser) SelectOptionByVirtualID(virtualID string, option string) (string, error) {
+	return b.SelectOptionByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID), option)
+}
+
+// Clicks a checkbox or radio button so that the page receives the same events as a user click. Returns the new checked state.
+func (b *Browser) ToggleByQuerySelector(query string) (bool, error) {
+	js := deepQueryJS + fmt.Sprintf(`function toggleByQuerySelector(query) {
+	const element = deepQuerySelector(query);
+	if (!element) {
+		throw new Error("element not found");
+	} else if (element.tagName !== 'INPUT' || (element.type !== 'checkbox' && element.type !== 'radio')) {
+		throw new Error("element is not a checkbox or radio button");
+	} else if (element.disabled) {
+		throw new Error("element is disabled");
+	}
+	element.click();
+	return element.checked;
+}
+toggleByQuerySelector('%s');`, query)
+	var checked bool
+	if err := b.run(chromedp.Evaluate(js, &checked)); err != nil {
+		return false, err
+	} else {
+		return checked, nil
+	}
+}
+
+func (b *Browser) ToggleByVirtualID(virtualID string) (bool, error) {
+	return b.ToggleByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID))
+}
+
+// Attaches local files to a file input through CDP so that the page receives the same events as a user upload.
+func (b *Browser) SetFileInputFilesByQuerySelector(query string, files []string) error {
+	js := deepQueryJS + fmt.Sprintf(`function getFileInputByQuerySelector(query, numFiles) {
+	const element = deepQuerySelector(query);
+	if (!element) {
+		throw new Error("element not found");
+	} else if (element.tagName !== 'INPUT' || element.type !== 'file') {
+		throw new Error("element is not a file input");
+	} else if (element.disabled) {
+		throw new Error("element is disabled");
+	} else if (numFiles > 1 && !element.multiple) {
+		throw new Error("element only accepts a single file");
+	}
+	return element;
+}
+getFileInputByQuerySelector(%s, %d);`, quoteJS(query), len(files))
+	var element *runtime.RemoteObject
+	return b.run(chromedp.Evaluate(js, &element), chromedp.ActionFunc(func(ctx context.Context) error {
+		defer func() {
+			if err := runtime.ReleaseObject(element.ObjectID).Do(ctx); err != nil {
+				log.Println("error releasing file input:", err)
+			}
+		}()
+		return dom.SetFileInputFiles(files).WithObjectID(element.ObjectID).Do(ctx)
+	}))
+}
+
+func (b *Browser) SetFileInputFilesByVirtualID(virtualID string, files []string) error {
+	return b.SetFileInputFilesByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID), files)
+}
+
+// Mirrors the live checked and selected state of form controls, and the names of attached files, into data
+// attributes so that it is visible in the captured html.
+func (b *Browser) syncFormState() error {
+	js := deepQueryJS + `function syncFormState() {
+	deepQuerySelectorAll('input[type="checkbox"], input[type="radio"]').forEach(element => {
+		element.setAttribute('data-checked', element.checked ? 'true' : 'false');
+	});
+	deepQuerySelectorAll('input[type="file"]').forEach(element => {
+		element.setAttribute('data-files', Array.from(element.files || []).map(file => file.name).join(', '));
+	});
+	deepQuerySelectorAll('option').forEach(element => {
+		element.setAttribute('data-selected', element.selected ? 'true' : 'false');
+	});
+}
+syncFormState();`
+	return b.run(chromedp.Evaluate(js, nil))
+}
+
+func (b *Browser) ScrollPage(direction ScrollDirection) error {
+	sign := 1
+	if direction == ScrollDirectionUp {
+		sign = -1
+	}
+	js := fmt.Sprintf(`function scrollPage(sign) {
+	window.scrollBy(0, sign * window.innerHeight * 0.9);
+}
+scrollPage(%d);`, sign)
+	return b.run(chromedp.Evaluate(js, nil))
+}
+
+func (b *Browser) ScrollIntoViewByQuerySelector(query string) error {
+	js := deepQueryJS + fmt.Sprintf(`function scrollIntoViewByQuerySelector(query) {
+	const element = deepQuerySelector(query);
+	if (element) {
+		element.scrollIntoView({block: 'center', inline: 'nearest'});
+	} else {
+		throw new Error("element not found");
+	}
+}
+scrollIntoViewByQuerySelector('%s');`, query)
+	return b.run(chromedp.Evaluate(js, nil))
+}
+
+func (b *Browser) ScrollIntoViewByVirtualID(virtualID string) error {
+	return b.ScrollIntoViewByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID))
+}
+
+type ScrollPosition struct {
+	ScrollY        float64 `json:"scrollY"`
+	ViewportHeight float64 `json:"viewportHeight"`
+	PageHeight     float64 `json:"pageHeight"`
+}
+
+func (p *ScrollPosition) String() string {
+	if p.PageHeight <= 0 {
+		return "the page is empty"
+	}
+	top := int(100 * p.ScrollY / p.PageHeight)
+	bottom := int(100 * (p.ScrollY + p.ViewportHeight) / p.PageHeight)
+	if bottom > 100 {
+		bottom = 100
+	}
+	return fmt.Sprintf("the viewport shows %d%%-%d%% of the page", top, bottom)
+}
+
+func (b *Browser) GetScrollPosition() (*ScrollPosition, error) {
+	js := `function getScrollPosition() {
+	return {
+		scrollY: window.scrollY,
+		viewportHeight: window.innerHeight,
+		pageHeight: document.documentElement.scrollHeight,
+	};
+}
+getScrollPosition();`
+	var position ScrollPosition
+	if err := b.run(chromedp.Evaluate(js, &position)); err != nil {
+		return nil, fmt.Errorf("error getting scroll position: %w", err)
+	} else {
+		return &position, nil
+	}
+}
+
+// Inserts comment markers into the document at the boundaries of the viewport. The markers are
+// interpreted by the html2md translator and must be removed with removeViewportMarkers.
+func (b *Browser) addViewportMarkers() error {
+	js := fmt.Sprintf(`function addViewportMarkers(aboveMarker, startMarker, endMarker) {
+	const walker = document.createTreeWalker(document.body, NodeFilter.SHOW_TEXT | NodeFilter.SHOW_ELEMENT);
+	let first = null;
+	let last = null;
+	let hasAbove = false;
+	let hasBelow = false;
+	while (walker.nextNode()) {
+		const node = walker.currentNode;
+		let rect;
+		if (node.nodeType === Node.TEXT_NODE) {
+			if (node.textContent.trim() === '') {
+				continue;
+			}
+			const range = document.createRange();
+			range.selectNodeContents(node);
+			rect = range.getBoundingClientRect();
+		} else if (node.hasAttribute('data-vid')) {
+			rect = node.getBoundingClientRect();
+		} else {
+			continue;
 		}
-		if (element.tagName === 'INPUT' || element.tagName === 'TEXTAREA') {
-			element.value = text;
-		} else if (element.textContent !== undefined) {
-			element.textContent = text;
+		if (rect.width === 0 && rect.height === 0) {
+			continue;
+		} else if (rect.bottom <= 0) {
+			hasAbove = true;
+		} else if (rect.top >= window.innerHeight) {
+			hasBelow = true;
 		} else {
-			throw new Error('Element cannot receive text');
+			if (first === null) {
+				first = node;
+			}
+			last = node;
+		}
+	}
+	const blockContainer = node => {
+		let element = node.nodeType === Node.TEXT_NODE ? node.parentElement : node;
+		while (element && element.parentElement && element.parentElement !== document.body && getComputedStyle(element).display.startsWith('inline')) {
+			element = element.parentElement;
 		}
-	} catch (error) {
-		throw new Error('Error in sendTextToSelector: ' + error.message);
+		return element && element !== document.body ? element : node;
+	};
+	if (hasAbove) {
+		document.body.prepend(document.createComment(aboveMarker));
+	}
+	if (first !== null) {
+		const container = blockContainer(first);
+		container.parentNode.insertBefore(document.createComment(startMarker), container);
+	}
+	if (last !== null && hasBelow) {
+		const container = blockContainer(last);
+		container.parentNode.insertBefore(document.createComment(endMarker), container.nextSibling);
 	}
 }
-sendTextToSelector('%s', '%s');`, query, text)
+addViewportMarkers('%s', '%s', '%s');`, html2md.ViewportMarkerAbove, html2md.ViewportMarkerStart, html2md.ViewportMarkerEnd)
 	return b.run(chromedp.Evaluate(js, nil))
 }
 
-func (b *Browser) SendTextByVirtualID(virtualID string, text string) error {
-	return b.SendTextByQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID), text)
+func (b *Browser) removeViewportMarkers() error {
+	js := fmt.Sprintf(`function removeViewportMarkers(markers) {
+	const walker = document.createTreeWalker(document.body, NodeFilter.SHOW_COMMENT);
+	const comments = [];
+	while (walker.nextNode()) {
+		if (markers.includes(walker.currentNode.data)) {
+			comments.push(walker.currentNode);
+		}
+	}
+	comments.forEach(comment => comment.remove());
+}
+removeViewportMarkers(['%s', '%s', '%s']);`, html2md.ViewportMarkerAbove, html2md.ViewportMarkerStart, html2md.ViewportMarkerEnd)
+	return b.run(chromedp.Evaluate(js, nil))
 }
 
 func (b *Browser) CheckElementTypeForQuerySelector(query string) (ElementType, error) {
-	js := fmt.Sprintf(`function checkElementTypeForQuerySelector(query) {
-	const element = document.querySelector(query);
+	js := deepQueryJS + fmt.Sprintf(`function checkElementTypeForQuerySelector(query) {
+	const element = deepQuerySelector(query);
 	if (element) {
-		if (element.tagName === 'INPUT') {
+		if (element.tagName === 'INPUT' && element.type === 'checkbox') {
+			return 'checkbox';
+		} else if (element.tagName === 'INPUT' && element.type === 'radio') {
+			return 'radio';
+		} else if (element.tagName === 'INPUT') {
 			return 'input';
+		} else if (element.tagName === 'SELECT') {
+			return 'select';
 		} else if (element.tagName === 'TEXTAREA') {
 			return 'textarea';
 		} else if (element.tagName === 'BUTTON') {
@@ -91,9 +413,114 @@ func (b *Browser) CheckElementTypeForVirtualID(virtualID string) (ElementType, e
 	return b.CheckElementTypeForQuerySelector(fmt.Sprintf("[data-vid=\"%s\"]", virtualID))
 }
 
+// Marks the visible interactive elements and all file inputs without a virtual id with their index in a
+// data-vid-pending attribute and returns their fingerprints in the same order.
+func (b *Browser) markElementsWithoutVirtualIDs() ([]*virtualid.ElementFingerprint, error) {
+	js := deepQueryJS + `function markElementsWithoutVirtualIDs() {
+	const normalize = text => (text || '').replace(/\s+/g, ' ').trim().slice(0, 100);
+	// the text of an element changes with its state, such as "Cart (1)" or "Following", so it is only used for
+	// elements without any other label, and without its digits, so that ids stay stable as the text changes
+	const labelOf = element => {
+		const attributeOf = name => (element.getAttribute(name) || '').trim();
+		const attributeLabel = ['placeholder', 'name', 'title', 'alt'].map(attributeOf).find(value => value !== '');
+		if (attributeOf('aria-label')) {
+			return attributeOf('aria-label');
+		} else if (element.labels && element.labels.length > 0 && element.labels[0].innerText.trim()) {
+			return element.labels[0].innerText;
+		} else if (attributeLabel) {
+			return attributeLabel;
+		} else if (element.tagName !== 'INPUT' && element.tagName !== 'TEXTAREA' && element.tagName !== 'SELECT' && element.innerText) {
+			return element.innerText.replace(/[0-9]+/g, '');
+		}
+		return '';
+	};
+	const domPathOf = element => {
+		const parts = [];
+		let current = element.parentElement || (element.parentNode && element.parentNode.host);
+		while (current) {
+			if (current.id) {
+				parts.unshift('#' + current.id);
+				break;
+			}
+			parts.unshift(current.tagName.toLowerCase());
+			current = current.parentElement || (current.parentNode && current.parentNode.host);
+		}
+		return parts.join('>');
+	};
+	const formContextOf = element => {
+		const form = element.form || element.closest('form');
+		if (!form) {
+			return '';
+		}
+		return form.id || form.getAttribute('name') || form.getAttribute('action') || 'form';
+	};
+	const hrefOf = element => {
+		if (!element.href) {
+			return '';
+		}
+		try {
+			const url = new URL(element.href);
+			return url.origin + url.pathname;
+		} catch (e) {
+			return element.getAttribute('href') || '';
+		}
+	};
+	const fingerprints = [];
+	deepQuerySelectorAll('button, input, a, textarea, select').forEach(element => {
+		element.removeAttribute('data-vid-pending');
+		if (element.hasAttribute('data-vid')) {
+			return;
+		}
+		// file inputs are usually hidden behind a styled label or button that opens them, so they are marked
+		// even if they are not displayed
+		const isFileInput = element.tagName === 'INPUT' && element.type === 'file';
+		if (element.offsetParent === null && !isFileInput) {
+			return;
+		}
+		element.setAttribute('data-vid-pending', fingerprints.length.toString());
+		fingerprints.push({
+			tag: element.tagName.toLowerCase(),
+			role: element.getAttribute('role') || '',
+			type: element.getAttribute('type') || '',
+			name: element.getAttribute('name') || '',
+			label: normalize(labelOf(element)),
+			href: hrefOf(element),
+			domPath: domPathOf(element),
+			formContext: formContextOf(element),
+		});
+	});
+	return fingerprints;
+}
+markElementsWithoutVirtualIDs();`
+	var fingerprints []*virtualid.ElementFingerprint
+	if err := b.run(chromedp.Evaluate(js, &fingerprints)); err != nil {
+		return nil, err
+	}
+	return fingerprints, nil
+}
+
+// Assigns virtual ids to the elements that were marked by markElementsWithoutVirtualIDs, by index.
+func (b *Browser) assignMarkedVirtualIDs(virtualIDs []string) error {
+	encoded, err := json.Marshal(virtualIDs)
+	if err != nil {
+		return err
+	}
+	js := deepQueryJS + fmt.Sprintf(`function assignMarkedVirtualIDs(virtualIDs) {
+	deepQuerySelectorAll('[data-vid-pending]').forEach(element => {
+		const i = parseInt(element.getAttribute('data-vid-pending'), 10);
+		element.removeAttribute('data-vid-pending');
+		if (i < virtualIDs.length && !element.hasAttribute('data-vid')) {
+			element.setAttribute('data-vid', virtualIDs[i]);
+		}
+	});
+}
+assignMarkedVirtualIDs(%s);`, encoded)
+	return b.run(chromedp.Evaluate(js, nil))
+}
+
 func (b *Browser) GetAllVisibleVirtualIDs() ([]string, error) {
-	js := `function getAllVisibleVirtualIDs() {
-	const elements = document.querySelectorAll('[data-vid]');
+	js := deepQueryJS + `function getAllVisibleVirtualIDs() {
+	const elements = deepQuerySelectorAll('[data-vid]');
 	const dataVids = Array.from(elements).map(element => element.getAttribute('data-vid'));
 	return dataVids;
 }
@@ -123,32 +550,6 @@ doesSupportAriaLabels();
 	}
 }
 
-const pageLoadWaitTimeoutMs = 10000
-
-func (b *Browser) waitForPageLoad() {
-	js := fmt.Sprintf(`function waitForPageLoad(timeoutMs) {
-	return new Promise((resolve, reject) => {
-		const timeout = setTimeout(() => {
-			reject(new Error('Timeout waiting for page load'));
-		}, timeoutMs);
-		if (document.readyState === 'loading') {
-			document.addEventListener('DOMContentLoaded', () => {
-				clearTimeout(timeout);
-				resolve();
-			})
-		} else {
-			clearTimeout(timeout);
-			resolve();
-		}
-	});
-	}
-}
-waitForPageLoad(%d);`, pageLoadWaitTimeoutMs)
-	if err := b.run(chromedp.Evaluate(js, nil)); err != nil {
-		log.Printf("Error waiting for page load: %v", err)
-	}
-}
-
 func (b *Browser) isPageLoaded() (bool, error) {
 	js := `function isPageLoaded() {
 	return document.readyState !== 'loading';
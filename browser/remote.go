@@ -0,0 +1,191 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// A page of a browser that was not launched by this process, such as a tab that the user has open.
+type RemoteTarget struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Picks the tab to attach to from the pages of a remote browser.
+type TargetSelector func(targets []*RemoteTarget) (*RemoteTarget, error)
+
+// Selects the page whose id is the query, or else the first page whose url or title contains the query. Selects
+// the first page if the query is empty.
+func TargetSelectorByQuery(query string) TargetSelector {
+	return func(targets []*RemoteTarget) (*RemoteTarget, error) {
+		if len(targets) == 0 {
+			return nil, errors.New("the remote browser has no open tabs")
+		} else if query == "" {
+			return targets[0], nil
+		}
+		for _, t := range targets {
+			if t.ID == query {
+				return t, nil
+			}
+		}
+		lowerQuery := strings.ToLower(query)
+		for _, t := range targets {
+			if strings.Contains(strings.ToLower(t.URL), lowerQuery) || strings.Contains(strings.ToLower(t.Title), lowerQuery) {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("no tab of the remote browser matches %q", query)
+	}
+}
+
+// Lists the open pages of the browser that serves the DevTools protocol at devtoolsURL, which is either the
+// http address of its remote debugging port, such as "http://localhost:9222", or a websocket debugger url.
+func ListRemoteTargets(ctx context.Context, devtoolsURL string) ([]*RemoteTarget, error) {
+	u, err := url.Parse(devtoolsURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing devtools url %s: %w", devtoolsURL, err)
+	}
+	listURL := &url.URL{Scheme: "http", Host: u.Host, Path: "/json/list"}
+	if u.Scheme == "https" || u.Scheme == "wss" {
+		listURL.Scheme = "https"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, listURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request to list targets: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error listing targets at %s: %w", listURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error listing targets at %s: %s", listURL, resp.Status)
+	}
+	var targets []*RemoteTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("error decoding targets: %w", err)
+	}
+	pages := []*RemoteTarget{}
+	for _, t := range targets {
+		if t.Type == "page" {
+			pages = append(pages, t)
+		}
+	}
+	return pages, nil
+}
+
+// Configures a browser that drives an already running Chrome. The zero value attaches to the first tab and permits
+// every page.
+type RemoteOptions struct {
+	// Picks the tab to attach to; defaults to the first tab.
+	TargetSelector TargetSelector
+	// Restricts the pages that the browser may visit; every page is permitted if it is nil.
+	NavigationPolicy *NavigationPolicy
+	// Only the request interception settings of the launch config have an effect, since the browser is already
+	// running.
+	LaunchConfig *LaunchConfig
+}
+
+// Creates a browser that drives an already running Chrome through the DevTools protocol at devtoolsURL instead
+// of launching one, attached to its first tab. See NewBrowserFromRemoteWithOptions.
+func NewBrowserFromRemote(ctx context.Context, devtoolsURL string) (*Browser, error) {
+	return NewBrowserFromRemoteWithOptions(ctx, devtoolsURL, nil)
+}
+
+// Creates a browser that drives an already running Chrome through the DevTools protocol at devtoolsURL instead
+// of launching one. The tab that is chosen by the target selector of the options is made the active tab. The tabs
+// that the browser opens, and the tabs that are opened by its pages, are driven like the tabs of a launched
+// browser. The other tabs of the user are listed, but their pages are left untouched until they are switched to. When the browser is canceled, the tabs and the browser of the user are left
+// open and the download behavior of the browser is restored. Since the browser was not launched by this process,
+// it cannot be switched between headless and headful mode with RunHeadless and RunHeadful.
+func NewBrowserFromRemoteWithOptions(ctx context.Context, devtoolsURL string, options *RemoteOptions) (*Browser, error) {
+	if options == nil {
+		options = &RemoteOptions{}
+	}
+	selector := options.TargetSelector
+	if selector == nil {
+		selector = TargetSelectorByQuery("")
+	}
+	b, err := newBrowserWithDefaults(options.NavigationPolicy, options.LaunchConfig)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := ListRemoteTargets(ctx, devtoolsURL)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selector(targets)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting tab: %w", err)
+	}
+	// canceling the first context of a remote allocator may close the browser, so it is never canceled
+	allocatorCtx, _ := chromedp.NewRemoteAllocator(context.WithoutCancel(ctx), devtoolsURL)
+	browserCtx, _ := chromedp.NewContext(allocatorCtx, chromedp.WithTargetID(target.ID(selected.ID)))
+	if err := chromedp.Run(browserCtx); err != nil {
+		return nil, fmt.Errorf("error attaching to tab %s: %w", selected.ID, err)
+	}
+	b.isRemote = true
+	b.isRunningHeadless = isHeadless(browserCtx)
+	chromedp.ListenBrowser(browserCtx, b.downloads.handleEvent)
+	if err := chromedp.Run(browserCtx, b.downloads.configure()); err != nil {
+		log.Println("error configuring downloads:", err)
+	}
+	b.resetTabs(browserCtx, b.releaseTabs)
+	return b, nil
+}
+
+// Reports whether the browser of the context runs in headless mode, which it reports in its product name.
+func isHeadless(ctx context.Context) bool {
+	c := chromedp.FromContext(ctx)
+	_, product, _, _, _, err := cdpbrowser.GetVersion().Do(cdp.WithExecutor(ctx, c.Browser))
+	if err != nil {
+		log.Println("error getting browser version; assuming that it is headful:", err)
+		return false
+	}
+	return strings.HasPrefix(product, "Headless")
+}
+
+// Restores the download behavior of a remote browser and detaches from all of its tabs without closing them.
+// chromedp closes the target of a context when the context is canceled, which must not happen to the tabs of the
+// user.
+func (b *Browser) releaseTabs() {
+	if err := chromedp.Run(b.browserCtx, b.downloads.restore()); err != nil {
+		log.Println("error restoring download behavior:", err)
+	}
+	var wg sync.WaitGroup
+	for _, t := range b.tabs {
+		if t.ownsBrowser || !t.isAttached() {
+			continue
+		}
+		wg.Add(1)
+		go func(t *tab) {
+			defer wg.Done()
+			if c := chromedp.FromContext(t.ctx); c != nil && c.Target != nil {
+				if err := target.DetachFromTarget().WithSessionID(c.Target.SessionID).Do(cdp.WithExecutor(t.ctx, c.Browser)); err != nil {
+					log.Printf("error detaching from tab %s: %v", t.id, err)
+				}
+				c.Target = nil
+			}
+			t.cancel()
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (b *Browser) IsRemote() bool {
+	return b.isRemote
+}
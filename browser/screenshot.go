@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/chromedp/chromedp"
+)
+
+type ScreenshotMode string
+
+const (
+	ScreenshotModeNone     ScreenshotMode = "none"
+	ScreenshotModeViewport ScreenshotMode = "viewport"
+	ScreenshotModeFullPage ScreenshotMode = "full_page"
+)
+
+type Screenshot struct {
+	// PNG encoded image
+	Data     []byte
+	FullPage bool
+	Location string
+}
+
+// Captures a PNG screenshot of the viewport or of the full page of the active tab.
+func (b *Browser) Screenshot(fullPage bool) (*Screenshot, error) {
+	var data []byte
+	var action chromedp.Action
+	if fullPage {
+		action = chromedp.FullScreenshot(&data, 100)
+	} else {
+		action = chromedp.CaptureScreenshot(&data)
+	}
+	if err := b.run(action); err != nil {
+		return nil, fmt.Errorf("error capturing screenshot: %w", err)
+	}
+	return &Screenshot{
+		Data:     data,
+		FullPage: fullPage,
+		Location: b.display.Location,
+	}, nil
+}
+
+func (b *Browser) SetScreenshotMode(mode ScreenshotMode) error {
+	if mode != ScreenshotModeNone && mode != ScreenshotModeViewport && mode != ScreenshotModeFullPage {
+		return fmt.Errorf("unsupported screenshot mode: %s", mode)
+	}
+	b.screenshotMode = mode
+	return nil
+}
+
+// Returns the screenshot that was captured after the last accepted action, or nil if screenshots are
+// disabled or the capture failed.
+func (b *Browser) GetLastScreenshot() *Screenshot {
+	return b.lastScreenshot
+}
+
+func (b *Browser) captureScreenshotAfterAction() {
+	b.lastScreenshot = nil
+	if b.screenshotMode == "" || b.screenshotMode == ScreenshotModeNone {
+		return
+	}
+	if screenshot, err := b.Screenshot(b.screenshotMode == ScreenshotModeFullPage); err != nil {
+		log.Println("error capturing screenshot after action:", err)
+	} else {
+		b.lastScreenshot = screenshot
+	}
+}
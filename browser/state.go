@@ -0,0 +1,208 @@
+package browser
+
+import (
+	"collaborativebrowser/utils/io"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// The state of a browser session that can be carried over to a new browser. The format of cookies and
+// localStorage matches the storage state files of other browser automation tools so that they can be shared.
+type StorageState struct {
+	Cookies []*network.Cookie `json:"cookies"`
+	Origins []*OriginState    `json:"origins"`
+	// The location of the active tab.
+	URL string `json:"url,omitempty"`
+}
+
+type OriginState struct {
+	Origin       string          `json:"origin"`
+	LocalStorage []*StorageEntry `json:"localStorage"`
+	// sessionStorage is only captured for the active tab, since it is scoped to a tab.
+	SessionStorage []*StorageEntry `json:"sessionStorage,omitempty"`
+}
+
+type StorageEntry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Writes the cookies, the localStorage and sessionStorage of the open tabs, and the current location to a
+// storage state JSON file.
+func (b *Browser) SaveState(path string) error {
+	state, err := b.captureState()
+	if err != nil {
+		return fmt.Errorf("error capturing browser state: %w", err)
+	}
+	bytes, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding browser state: %w", err)
+	}
+	if err := io.WriteBytesToFile(path, bytes); err != nil {
+		return fmt.Errorf("error writing browser state to %s: %w", path, err)
+	}
+	return nil
+}
+
+// Restores the cookies and storage from a storage state JSON file and navigates to its location, if it has one.
+func (b *Browser) LoadState(path string) error {
+	bytes, err := io.ReadFileAsBytes(path)
+	if err != nil {
+		return fmt.Errorf("error reading browser state from %s: %w", path, err)
+	}
+	var state StorageState
+	if err := json.Unmarshal(bytes, &state); err != nil {
+		return fmt.Errorf("error parsing browser state from %s: %w", path, err)
+	}
+	if err := b.restoreState(&state); err != nil {
+		return fmt.Errorf("error restoring browser state: %w", err)
+	}
+	if state.URL == "" {
+		return nil
+	}
+	return b.Navigate(state.URL)
+}
+
+func (b *Browser) captureState() (*StorageState, error) {
+	state := &StorageState{
+		URL: b.display.Location,
+	}
+	if err := b.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		cookies, err := storage.GetCookies().Do(ctx)
+		state.Cookies = cookies
+		return err
+	})); err != nil {
+		return nil, fmt.Errorf("error getting cookies: %w", err)
+	}
+	origins := make(map[string]*OriginState)
+	for _, t := range b.tabs {
+		if !t.isAttached() {
+			continue
+		}
+		var origin OriginState
+		js := `(() => {
+	const entries = storage => Object.keys(storage).map(name => ({name, value: storage.getItem(name)}));
+	return {origin: location.origin, localStorage: entries(localStorage), sessionStorage: entries(sessionStorage)};
+})();`
+		if err := chromedp.Run(t.ctx, chromedp.Evaluate(js, &origin)); err != nil {
+			// pages such as about:blank or error pages do not have storage
+			log.Printf("error getting storage of tab %s: %v", t.id, err)
+			continue
+		}
+		if origin.Origin == "" || origin.Origin == "null" {
+			continue
+		}
+		if t != b.activeTab {
+			origin.SessionStorage = nil
+		}
+		if existing, ok := origins[origin.Origin]; ok {
+			if t == b.activeTab {
+				existing.SessionStorage = origin.SessionStorage
+			}
+			continue
+		}
+		origins[origin.Origin] = &origin
+		state.Origins = append(state.Origins, &origin)
+	}
+	return state, nil
+}
+
+// Restores cookies and storage into the active tab. Storage can only be written by a document of its origin,
+// so each origin is visited with a blank page that is served by request interception instead of the network.
+// The active tab is left on the last visited origin.
+func (b *Browser) restoreState(state *StorageState) error {
+	cookies := make([]*network.CookieParam, 0, len(state.Cookies))
+	for _, c := range state.Cookies {
+		cookie := &network.CookieParam{
+			Name:         c.Name,
+			Value:        c.Value,
+			Domain:       c.Domain,
+			Path:         c.Path,
+			Secure:       c.Secure,
+			HTTPOnly:     c.HTTPOnly,
+			SameSite:     c.SameSite,
+			Priority:     c.Priority,
+			SameParty:    c.SameParty,
+			SourceScheme: c.SourceScheme,
+			SourcePort:   c.SourcePort,
+			PartitionKey: c.PartitionKey,
+		}
+		if !c.Session && c.Expires > 0 {
+			seconds, fraction := math.Modf(c.Expires)
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(seconds), int64(fraction*1e9)))
+			cookie.Expires = &expires
+		}
+		cookies = append(cookies, cookie)
+	}
+	if len(cookies) > 0 {
+		if err := b.run(storage.SetCookies(cookies)); err != nil {
+			return fmt.Errorf("error setting cookies: %w", err)
+		}
+	}
+	for _, origin := range state.Origins {
+		if len(origin.LocalStorage) == 0 && len(origin.SessionStorage) == 0 {
+			continue
+		}
+		if err := b.restoreOriginStorage(origin); err != nil {
+			log.Printf("error restoring storage of %s: %v", origin.Origin, err)
+		}
+	}
+	return nil
+}
+
+func (b *Browser) restoreOriginStorage(origin *OriginState) error {
+	u, err := url.Parse(origin.Origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("unsupported origin: %s", origin.Origin)
+	}
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		if ev, ok := ev.(*fetch.EventRequestPaused); ok {
+			go func() {
+				c := chromedp.FromContext(ctx)
+				body := base64.StdEncoding.EncodeToString([]byte("<html><head></head><body></body></html>"))
+				if err := fetch.FulfillRequest(ev.RequestID, 200).
+					WithResponseHeaders([]*fetch.HeaderEntry{{Name: "Content-Type", Value: "text/html"}}).
+					WithBody(body).
+					Do(cdp.WithExecutor(ctx, c.Target)); err != nil {
+					log.Printf("error serving blank page for %s: %v", origin.Origin, err)
+				}
+			}()
+		}
+	})
+	encoded, err := json.Marshal(origin)
+	if err != nil {
+		return fmt.Errorf("error encoding storage: %w", err)
+	}
+	js := fmt.Sprintf(`(state => {
+	(state.localStorage || []).forEach(entry => localStorage.setItem(entry.name, entry.value));
+	(state.sessionStorage || []).forEach(entry => sessionStorage.setItem(entry.name, entry.value));
+})(%s);`, encoded)
+	if b.interceptor != nil {
+		b.interceptor.suspended.Store(true)
+		defer b.interceptor.suspended.Store(false)
+	}
+	if err := b.run(fetch.Enable().WithPatterns([]*fetch.RequestPattern{{URLPattern: origin.Origin + "/*"}})); err != nil {
+		return fmt.Errorf("error enabling request interception: %w", err)
+	}
+	defer func() {
+		// restores the request interception of the browser, if any
+		if err := b.enableRequestInterception(b.activeTab); err != nil {
+			log.Println("error restoring request interception:", err)
+		}
+	}()
+	return b.run(chromedp.Navigate(origin.Origin+"/"), chromedp.Evaluate(js, nil))
+}
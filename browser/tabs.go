@@ -0,0 +1,337 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+type tab struct {
+	id       string
+	targetID target.ID
+	// nil for the tabs of a remote browser that the user opened, which are left untouched until they are switched to
+	ctx      context.Context
+	cancel   context.CancelFunc
+	title    string
+	location string
+	network  *networkTracker
+	dialogs  *dialogTracker
+
+	// the first tab owns the browser process, so its context must not be canceled when it is closed
+	ownsBrowser bool
+}
+
+type TabDisplay struct {
+	ID       string
+	Title    string
+	Location string
+	Active   bool
+}
+
+const TabIDPrefix = "tab-"
+
+func (b *Browser) newTab(ctx context.Context, cancel context.CancelFunc, targetID target.ID) *tab {
+	t := b.newDetachedTab(targetID)
+	b.attachTab(t, ctx, cancel)
+	return t
+}
+
+// Adds a tab that is listed and can be switched to, but whose page is not driven until it is attached.
+func (b *Browser) newDetachedTab(targetID target.ID) *tab {
+	t := &tab{
+		id:       fmt.Sprintf("%s%d", TabIDPrefix, b.tabCounter),
+		targetID: targetID,
+		network:  newNetworkTracker(),
+		dialogs:  &dialogTracker{},
+	}
+	b.tabCounter++
+	b.tabs = append(b.tabs, t)
+	return t
+}
+
+// Reports whether the page of the tab is driven by the browser, which handles its events, intercepts its
+// requests, and emulates its profile.
+func (t *tab) isAttached() bool {
+	return t.ctx != nil
+}
+
+func (b *Browser) attachTab(t *tab, ctx context.Context, cancel context.CancelFunc) {
+	t.ctx = ctx
+	t.cancel = cancel
+	chromedp.ListenTarget(ctx, t.network.handleEvent)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		b.handleDialogEvent(t, ev)
+	})
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		b.console.handleEvent(t, ev)
+	})
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		b.networkLog.handleEvent(t, ev)
+	})
+	b.listenForInterceptedRequests(t)
+	b.applyEmulationProfile(t)
+}
+
+// Attaches to the page of a detached tab so that it can be driven.
+func (b *Browser) attachExistingTab(t *tab) error {
+	ctx, cancel := chromedp.NewContext(b.browserCtx, chromedp.WithTargetID(t.targetID))
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return fmt.Errorf("error attaching to tab %s: %w", t.id, err)
+	}
+	b.attachTab(t, ctx, cancel)
+	return nil
+}
+
+// Replaces all tabs with the first tab of a newly launched browser.
+func (b *Browser) resetTabs(browserCtx context.Context, cancel context.CancelFunc) {
+	b.browserCtx = browserCtx
+	b.cancel = cancel
+	b.tabs = nil
+	t := b.newTab(browserCtx, cancel, "")
+	t.ownsBrowser = true
+	b.setActiveTab(t)
+}
+
+func (b *Browser) setActiveTab(t *tab) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ctx = t.ctx
+	b.activeTab = t
+}
+
+// Makes a tab the active tab, attaching to it first if it is detached.
+func (b *Browser) activateTab(t *tab) error {
+	if !t.isAttached() {
+		if err := b.attachExistingTab(t); err != nil {
+			return err
+		}
+	}
+	b.setActiveTab(t)
+	if err := b.run(page.BringToFront()); err != nil {
+		return fmt.Errorf("error bringing tab %s to front: %w", t.id, err)
+	}
+	return nil
+}
+
+func (b *Browser) getTab(id string) (*tab, error) {
+	for _, t := range b.tabs {
+		if t.id == id {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("tab does not exist: %s", id)
+}
+
+// Returns the most recently added tab that is attached, or nil if there is none.
+func (b *Browser) lastAttachedTab() *tab {
+	for i := len(b.tabs) - 1; i >= 0; i-- {
+		if b.tabs[i].isAttached() {
+			return b.tabs[i]
+		}
+	}
+	return nil
+}
+
+func (b *Browser) countAttachedTabs() int {
+	count := 0
+	for _, t := range b.tabs {
+		if t.isAttached() {
+			count++
+		}
+	}
+	return count
+}
+
+// Opens a new tab, makes it the active tab, and navigates it to the url if one is given.
+func (b *Browser) OpenTab(URL string) (string, error) {
+	ctx, cancel := chromedp.NewContext(b.browserCtx)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return "", fmt.Errorf("error opening tab: %w", err)
+	}
+	t := b.newTab(ctx, cancel, chromedp.FromContext(ctx).Target.TargetID)
+	if err := b.activateTab(t); err != nil {
+		return "", err
+	}
+	if URL != "" {
+		if err := b.Navigate(URL); err != nil {
+			return t.id, fmt.Errorf("error navigating new tab %s: %w", t.id, err)
+		}
+		return t.id, nil
+	}
+	return t.id, b.updateDisplay()
+}
+
+func (b *Browser) SwitchTab(id string) error {
+	if t, err := b.getTab(id); err != nil {
+		return err
+	} else if err := b.activateTab(t); err != nil {
+		return err
+	}
+	return b.updateDisplay()
+}
+
+// Closes a tab by id, or the active tab if the id is empty. The last attached tab cannot be closed, and neither can
+// detached tabs, which belong to the user until they are switched to.
+func (b *Browser) CloseTab(id string) error {
+	if id == "" {
+		id = b.activeTab.id
+	}
+	t, err := b.getTab(id)
+	if err != nil {
+		return err
+	} else if !t.isAttached() {
+		return fmt.Errorf("cannot close tab %s, which was not opened by the agent; switch to it first", id)
+	} else if b.countAttachedTabs() == 1 {
+		return fmt.Errorf("cannot close the last tab: %s", id)
+	} else if err := chromedp.Run(t.ctx, page.Close()); err != nil {
+		return fmt.Errorf("error closing tab %s: %w", id, err)
+	}
+	if !t.ownsBrowser {
+		t.cancel()
+	}
+	b.removeTab(t)
+	if t == b.activeTab {
+		if err := b.activateTab(b.lastAttachedTab()); err != nil {
+			return err
+		}
+	}
+	return b.updateDisplay()
+}
+
+func (b *Browser) removeTab(t *tab) {
+	tabs := []*tab{}
+	for _, other := range b.tabs {
+		if other != t {
+			tabs = append(tabs, other)
+		}
+	}
+	b.tabs = tabs
+}
+
+func (b *Browser) ActiveTabID() string {
+	if b.activeTab == nil {
+		return ""
+	}
+	return b.activeTab.id
+}
+
+// Reconciles the tab list with the page targets of the browser. Tabs that were opened by a page, such as
+// links with target=_blank or popups, are attached and followed automatically. Tabs that were closed by a
+// page are removed, and tabs that were opened at urls that are not permitted by the navigation policy are closed.
+// In a remote browser, only the tabs that were opened by attached tabs are attached; the other tabs of the user
+// are added detached.
+func (b *Browser) syncTabs() error {
+	if len(b.tabs) == 0 {
+		return nil
+	}
+	for _, t := range b.tabs {
+		if t.targetID == "" && t.isAttached() {
+			if c := chromedp.FromContext(t.ctx); c != nil && c.Target != nil {
+				t.targetID = c.Target.TargetID
+			}
+		}
+	}
+	if b.tabs[0].targetID == "" && len(b.tabs) == 1 {
+		// the browser has not been started yet
+		return nil
+	}
+	infos, err := chromedp.Targets(b.browserCtx)
+	if err != nil {
+		return fmt.Errorf("error getting targets: %w", err)
+	}
+	known := make(map[target.ID]*tab)
+	for _, t := range b.tabs {
+		known[t.targetID] = t
+	}
+	pages := make(map[target.ID]*target.Info)
+	var discovered []*tab
+	for _, info := range infos {
+		if info.Type != "page" {
+			continue
+		}
+		pages[info.TargetID] = info
+		if t, ok := known[info.TargetID]; ok {
+			t.title = info.Title
+			t.location = info.URL
+			continue
+		}
+		if opener := known[info.OpenerID]; b.isRemote && (info.OpenerID == "" || opener == nil || !opener.isAttached()) {
+			t := b.newDetachedTab(info.TargetID)
+			t.title = info.Title
+			t.location = info.URL
+			known[info.TargetID] = t
+			continue
+		}
+		ctx, cancel := chromedp.NewContext(b.browserCtx, chromedp.WithTargetID(info.TargetID))
+		if err := chromedp.Run(ctx); err != nil {
+			cancel()
+			log.Printf("error attaching to new tab %s: %v", info.TargetID, err)
+			continue
+		}
+		t := b.newTab(ctx, cancel, info.TargetID)
+		t.title = info.Title
+		t.location = info.URL
+		known[info.TargetID] = t
+		discovered = append(discovered, t)
+	}
+	for _, t := range b.tabs {
+		if _, ok := pages[t.targetID]; !ok {
+			if t.isAttached() && !t.ownsBrowser {
+				t.cancel()
+			}
+			b.removeTab(t)
+		}
+	}
+	var opened *tab
+	for _, t := range b.closeBlockedTabs(discovered) {
+		if info := pages[t.targetID]; info != nil && info.OpenerID != "" {
+			opened = t
+		}
+	}
+	if opened != nil {
+		return b.activateTab(opened)
+	} else if b.lastAttachedTab() == nil {
+		_, err := b.OpenTab("")
+		return err
+	} else if _, err := b.getTab(b.activeTab.id); err != nil {
+		return b.activateTab(b.lastAttachedTab())
+	}
+	return nil
+}
+
+func (b *Browser) getTabDisplays() []*TabDisplay {
+	displays := make([]*TabDisplay, len(b.tabs))
+	for i, t := range b.tabs {
+		displays[i] = &TabDisplay{
+			ID:       t.id,
+			Title:    t.title,
+			Location: t.location,
+			Active:   t == b.activeTab,
+		}
+	}
+	return displays
+}
+
+func renderTabsHeader(tabs []*TabDisplay) string {
+	lines := []string{"----- TABS -----"}
+	for _, t := range tabs {
+		var active string
+		if t.Active {
+			active = " (active)"
+		}
+		title := t.Title
+		if title == "" {
+			title = "untitled"
+		}
+		lines = append(lines, fmt.Sprintf("- %s%s: %s - %s", t.ID, active, title, t.Location))
+	}
+	lines = append(lines, "----- END TABS -----")
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+package browser
+
+import (
+	"collaborativebrowser/browser/virtualid"
+	"collaborativebrowser/trajectory"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sets the local directories that files can be uploaded from. Uploads are not possible until at least one
+// directory is set.
+func (b *Browser) SetUploadDirectories(dirs []string) error {
+	resolved := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err != nil {
+			return fmt.Errorf("error resolving upload directory %s: %w", dir, err)
+		} else if abs, err = filepath.EvalSymlinks(abs); err != nil {
+			return fmt.Errorf("error resolving upload directory %s: %w", dir, err)
+		} else if info, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("error reading upload directory %s: %w", dir, err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("upload directory is not a directory: %s", dir)
+		} else {
+			resolved = append(resolved, abs)
+		}
+	}
+	b.uploadDirs = resolved
+	return nil
+}
+
+// Resolves a file to an absolute path inside one of the upload directories. Relative paths are looked up in
+// each upload directory in order.
+func (b *Browser) resolveUploadFile(file string) (string, error) {
+	if len(b.uploadDirs) == 0 {
+		return "", errors.New("no upload directories are configured")
+	}
+	candidates := []string{file}
+	if !filepath.IsAbs(file) {
+		candidates = make([]string, len(b.uploadDirs))
+		for i, dir := range b.uploadDirs {
+			candidates[i] = filepath.Join(dir, file)
+		}
+	}
+	for _, candidate := range candidates {
+		// symlinks are resolved so that they cannot point outside of the upload directories
+		resolved, err := filepath.EvalSymlinks(candidate)
+		if err != nil {
+			continue
+		}
+		for _, dir := range b.uploadDirs {
+			if rel, err := filepath.Rel(dir, resolved); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				if info, err := os.Stat(resolved); err != nil || info.IsDir() {
+					return "", fmt.Errorf("not a file: %s", file)
+				}
+				return resolved, nil
+			}
+		}
+		return "", fmt.Errorf("file is outside of the upload directories: %s", file)
+	}
+	return "", fmt.Errorf("file not found in the upload directories: %s", file)
+}
+
+// Attaches files from the upload directories to a file input, replacing any files that were attached before.
+func (b *Browser) UploadFiles(id virtualid.VirtualID, files []string) error {
+	if len(files) == 0 {
+		return errors.New("at least one file must be given")
+	}
+	resolved := make([]string, len(files))
+	for i, file := range files {
+		path, err := b.resolveUploadFile(file)
+		if err != nil {
+			return err
+		}
+		resolved[i] = path
+	}
+	if !b.vIDGenerator.IsValidVirtualID(id) {
+		return fmt.Errorf("invalid virtual id: %s", id)
+	} else if exists, err := b.DoesVirtualIDExist(string(id)); err != nil {
+		return fmt.Errorf("error checking if virtual id exists: %w", err)
+	} else if !exists {
+		return fmt.Errorf("virtual id does not exist: %s", id)
+	} else if err := b.SetFileInputFilesByVirtualID(string(id), resolved); err != nil {
+		return fmt.Errorf("error setting files by virtual id: %w", err)
+	}
+	b.waitForPageToSettle(trajectory.BrowserActionTypeUploadFile)
+	return b.updateDisplay()
+}
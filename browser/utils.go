@@ -1,11 +1,10 @@
 package browser
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
-	"strings"
 )
 
 func IsValidURL(URL string) (bool, error) {
@@ -17,30 +16,11 @@ func IsValidURL(URL string) (bool, error) {
 	return true, nil
 }
 
-// Note: This only works for HTTP and HTTPS URLs.
-func GetCanonicalURL(URL string) (string, error) {
-	if !strings.HasPrefix(URL, "http://") && !strings.HasPrefix(URL, "https://") {
-		URL = "https://" + URL
-	}
-	resp, err := http.Head(URL)
+// Quotes a string so that it can be safely embedded as a literal in a JavaScript snippet.
+func quoteJS(s string) string {
+	b, err := json.Marshal(s)
 	if err != nil {
-		return "", err
-	}
-	resp.Body.Close()
-	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
-		loc, err := resp.Location()
-		if err != nil {
-			return "", err
-		}
-		return loc.String(), nil
-	}
-	if !strings.Contains(URL, "://www.") {
-		wwwVersion := strings.Replace(URL, "://", "://www.", 1)
-		resp, err := http.Head(wwwVersion)
-		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 400 {
-			resp.Body.Close()
-			return wwwVersion, nil
-		}
+		return `""`
 	}
-	return URL, nil
+	return string(b)
 }
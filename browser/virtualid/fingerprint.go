@@ -0,0 +1,72 @@
+package virtualid
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+const fingerprintIDLength = 6
+
+// A VirtualIDGenerator that derives ids from a hash of the fingerprint of an element, so that an element gets
+// the same id when it is re-rendered or when unrelated parts of the page change. Elements with the same
+// fingerprint are told apart by the order in which they are generated.
+type FingerprintVirtualIDGenerator struct{}
+
+func NewFingerprintVirtualIDGenerator() VirtualIDGenerator {
+	return &FingerprintVirtualIDGenerator{}
+}
+
+func (g *FingerprintVirtualIDGenerator) Generate(element *ElementFingerprint, isTaken func(VirtualID) bool) VirtualID {
+	key := fingerprintKey(element)
+	for salt := 0; ; salt++ {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		if salt > 0 {
+			fmt.Fprintf(h, "#%d", salt)
+		}
+		newID := VirtualID(VirtualIDPrefix + encodeFingerprintHash(h.Sum64()))
+		if isTaken == nil || !isTaken(newID) {
+			return newID
+		}
+	}
+}
+
+func (g *FingerprintVirtualIDGenerator) IsValidVirtualID(id VirtualID) bool {
+	if !IsValidBaseVirtualID(id) {
+		return false
+	}
+	n := strings.TrimPrefix(string(id), VirtualIDPrefix)
+	if len(n) != fingerprintIDLength {
+		return false
+	}
+	_, err := strconv.ParseUint(n, 36, 64)
+	return err == nil
+}
+
+func fingerprintKey(element *ElementFingerprint) string {
+	if element == nil {
+		return ""
+	}
+	return strings.Join([]string{
+		element.Tag,
+		element.Role,
+		element.Type,
+		element.Name,
+		strings.ToLower(strings.Join(strings.Fields(element.Label), " ")),
+		element.Href,
+		element.DOMPath,
+		element.FormContext,
+	}, "\x00")
+}
+
+// Encodes a hash as a fixed length base 36 string.
+func encodeFingerprintHash(sum uint64) string {
+	var max uint64 = 1
+	for i := 0; i < fingerprintIDLength; i++ {
+		max *= 36
+	}
+	encoded := strconv.FormatUint(sum%max, 36)
+	return strings.Repeat("0", fingerprintIDLength-len(encoded)) + encoded
+}
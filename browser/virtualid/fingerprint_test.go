@@ -0,0 +1,128 @@
+package virtualid
+
+import "testing"
+
+func newButton(label string) *ElementFingerprint {
+	return &ElementFingerprint{
+		Tag:         "button",
+		Type:        "submit",
+		Label:       label,
+		DOMPath:     "#main>form",
+		FormContext: "search",
+	}
+}
+
+func TestFingerprintGeneratorIsStable(t *testing.T) {
+	tests := []struct {
+		name string
+		a    *ElementFingerprint
+		b    *ElementFingerprint
+		same bool
+	}{
+		{"same fingerprint", newButton("Search"), newButton("Search"), true},
+		{"label case", newButton("Search"), newButton("SEARCH"), true},
+		{"label whitespace", newButton("Add  to\ncart"), newButton(" Add to cart "), true},
+		{"different label", newButton("Search"), newButton("Submit"), false},
+		{"different tag", newButton("Search"), &ElementFingerprint{Tag: "a", Type: "submit", Label: "Search", DOMPath: "#main>form", FormContext: "search"}, false},
+		{"different dom path", newButton("Search"), &ElementFingerprint{Tag: "button", Type: "submit", Label: "Search", DOMPath: "#footer>form", FormContext: "search"}, false},
+		{"different href", &ElementFingerprint{Tag: "a", Href: "https://example.com/a"}, &ElementFingerprint{Tag: "a", Href: "https://example.com/b"}, false},
+		// fields are separated so that text cannot move from one field to the next without changing the id
+		{"shifted fields", &ElementFingerprint{Tag: "a", Role: "bc"}, &ElementFingerprint{Tag: "ab", Role: "c"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := NewFingerprintVirtualIDGenerator().Generate(test.a, nil)
+			b := NewFingerprintVirtualIDGenerator().Generate(test.b, nil)
+			if (a == b) != test.same {
+				t.Errorf("got ids %s and %s, want same=%t", a, b, test.same)
+			}
+		})
+	}
+}
+
+func TestFingerprintGeneratorAvoidsTakenIDs(t *testing.T) {
+	g := NewFingerprintVirtualIDGenerator()
+	taken := make(map[VirtualID]bool)
+	isTaken := func(id VirtualID) bool {
+		return taken[id]
+	}
+	// identical elements, such as the rows of a list with the same buttons, get distinct ids in order
+	ids := []VirtualID{}
+	for i := 0; i < 5; i++ {
+		id := g.Generate(newButton("Remove"), isTaken)
+		if taken[id] {
+			t.Fatalf("generated taken id %s", id)
+		}
+		taken[id] = true
+		ids = append(ids, id)
+	}
+	if ids[0] != g.Generate(newButton("Remove"), nil) {
+		t.Errorf("first id %s is not the id of the fingerprint", ids[0])
+	}
+	// a new generator reproduces the same ids in the same order, as when the page is rendered again
+	again := NewFingerprintVirtualIDGenerator()
+	retaken := make(map[VirtualID]bool)
+	for i, want := range ids {
+		got := again.Generate(newButton("Remove"), func(id VirtualID) bool { return retaken[id] })
+		if got != want {
+			t.Errorf("id %d = %s, want %s", i, got, want)
+		}
+		retaken[got] = true
+	}
+}
+
+func TestFingerprintGeneratorIDsAreValid(t *testing.T) {
+	g := NewFingerprintVirtualIDGenerator()
+	labels := []string{"", "Search", "日本語", "a very long label that goes on and on"}
+	for _, label := range labels {
+		id := g.Generate(newButton(label), nil)
+		if !g.IsValidVirtualID(id) {
+			t.Errorf("generated id %s for label %q is not valid", id, label)
+		}
+		if len(id) != len(VirtualIDPrefix)+fingerprintIDLength {
+			t.Errorf("generated id %s for label %q has length %d", id, label, len(id))
+		}
+	}
+}
+
+func TestFingerprintGeneratorIsValidVirtualID(t *testing.T) {
+	tests := []struct {
+		id   VirtualID
+		want bool
+	}{
+		{"vid-0a1b2c", true},
+		{"vid-000000", true},
+		{"vid-zzzzzz", true},
+		{"vid-", false},
+		{"vid-12345", false},
+		{"vid-1234567", false},
+		{"vid-12345!", false},
+		{"0a1b2c", false},
+		{"", false},
+	}
+	g := NewFingerprintVirtualIDGenerator()
+	for _, test := range tests {
+		if got := g.IsValidVirtualID(test.id); got != test.want {
+			t.Errorf("IsValidVirtualID(%q) = %t, want %t", test.id, got, test.want)
+		}
+	}
+}
+
+func TestEncodeFingerprintHash(t *testing.T) {
+	tests := []struct {
+		sum  uint64
+		want string
+	}{
+		{0, "000000"},
+		{35, "00000z"},
+		{36, "000010"},
+		// hashes are reduced to six base 36 digits
+		{2176782336, "000000"},
+		{2176782335, "zzzzzz"},
+	}
+	for _, test := range tests {
+		if got := encodeFingerprintHash(test.sum); got != test.want {
+			t.Errorf("encodeFingerprintHash(%d) = %s, want %s", test.sum, got, test.want)
+		}
+	}
+}
@@ -12,10 +12,28 @@ const VirtualIDPrefix = "vid-"
 const VirtualIDDataAttr = "data-vid"
 
 type VirtualIDGenerator interface {
-	Generate() VirtualID
+	// Generates a virtual id for an element. isTaken reports the ids that are already in use on the page, which
+	// must not be returned.
+	Generate(element *ElementFingerprint, isTaken func(VirtualID) bool) VirtualID
 	IsValidVirtualID(id VirtualID) bool
 }
 
+// Describes an element that needs a virtual id. Generators may derive ids from it.
+type ElementFingerprint struct {
+	Tag  string `json:"tag"`
+	Role string `json:"role"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// The label of the element: its aria-label, label, placeholder, name, title, or alt text, or else its text without
+	// digits.
+	Label string `json:"label"`
+	Href  string `json:"href"`
+	// The tag names of the ancestors of the element up to the nearest ancestor with an id, such as "#main>ul>li".
+	DOMPath string `json:"domPath"`
+	// The id, name, or action of the form that contains the element.
+	FormContext string `json:"formContext"`
+}
+
 type IncrIntVirtualIDGenerator struct {
 	Cur int
 }
@@ -25,10 +43,14 @@ func NewIncrIntVirtualIDGenerator() VirtualIDGenerator {
 	return &IncrIntVirtualIDGenerator{Cur: 0}
 }
 
-func (g *IncrIntVirtualIDGenerator) Generate() VirtualID {
-	newID := VirtualID(fmt.Sprintf("%s%d", VirtualIDPrefix, g.Cur))
-	g.Cur++
-	return newID
+func (g *IncrIntVirtualIDGenerator) Generate(_ *ElementFingerprint, isTaken func(VirtualID) bool) VirtualID {
+	for {
+		newID := VirtualID(fmt.Sprintf("%s%d", VirtualIDPrefix, g.Cur))
+		g.Cur++
+		if isTaken == nil || !isTaken(newID) {
+			return newID
+		}
+	}
 }
 
 func (g *IncrIntVirtualIDGenerator) IsValidVirtualID(id VirtualID) bool {
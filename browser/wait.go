@@ -0,0 +1,258 @@
+package browser
+
+import (
+	"collaborativebrowser/trajectory"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+type WaitOptions struct {
+	// The maximum time to wait for the page to settle after an action that does not have its own timeout.
+	DefaultTimeout time.Duration
+	// Timeouts for specific action types.
+	ActionTimeouts map[trajectory.BrowserActionType]time.Duration
+	// The network is idle once at most MaxInflightRequests requests have been in flight for NetworkIdleDuration.
+	// A few requests are tolerated by default, since long polls and analytics requests may never finish.
+	NetworkIdleDuration time.Duration
+	MaxInflightRequests int
+	// The DOM is stable once it has not been mutated for DOMQuietDuration.
+	DOMQuietDuration time.Duration
+	// Whether to wait for network idle and DOM stability. If false, only the document load is awaited.
+	WaitForNetworkAndDOM bool
+}
+
+const (
+	DefaultWaitTimeout         = 5 * time.Second
+	DefaultNavigationTimeout   = 15 * time.Second
+	DefaultNetworkIdleDuration = 500 * time.Millisecond
+	DefaultMaxInflightRequests = 2
+	DefaultDOMQuietDuration    = 300 * time.Millisecond
+	DefaultWaitForTimeout      = 10 * time.Second
+	waitPollInterval           = 100 * time.Millisecond
+)
+
+func DefaultWaitOptions() *WaitOptions {
+	return &WaitOptions{
+		DefaultTimeout: DefaultWaitTimeout,
+		ActionTimeouts: map[trajectory.BrowserActionType]time.Duration{
+			trajectory.BrowserActionTypeNavigate:  DefaultNavigationTimeout,
+			trajectory.BrowserActionTypeGoBack:    DefaultNavigationTimeout,
+			trajectory.BrowserActionTypeGoForward: DefaultNavigationTimeout,
+			trajectory.BrowserActionTypeReload:    DefaultNavigationTimeout,
+			trajectory.BrowserActionTypeOpenTab:   DefaultNavigationTimeout,
+			trajectory.BrowserActionTypeScroll:    2 * time.Second,
+		},
+		NetworkIdleDuration:  DefaultNetworkIdleDuration,
+		MaxInflightRequests:  DefaultMaxInflightRequests,
+		DOMQuietDuration:     DefaultDOMQuietDuration,
+		WaitForNetworkAndDOM: true,
+	}
+}
+
+func (o *WaitOptions) timeoutFor(actionType trajectory.BrowserActionType) time.Duration {
+	if timeout, ok := o.ActionTimeouts[actionType]; ok {
+		return timeout
+	}
+	return o.DefaultTimeout
+}
+
+func (b *Browser) SetWaitOptions(options *WaitOptions) {
+	if options == nil {
+		options = DefaultWaitOptions()
+	}
+	b.waitOptions = options
+}
+
+// Tracks the in-flight network requests of a tab through CDP network events. The requests of a page are forgotten
+// when its main frame navigates, since the browser does not always report the end of requests that a page leaves.
+type networkTracker struct {
+	mu           sync.Mutex
+	inflight     map[network.RequestID]struct{}
+	lastActivity time.Time
+}
+
+func newNetworkTracker() *networkTracker {
+	return &networkTracker{
+		inflight:     make(map[network.RequestID]struct{}),
+		lastActivity: time.Now(),
+	}
+}
+
+func (t *networkTracker) handleEvent(ev interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// event streams never finish and beacons are not awaited by the page, so they would prevent the network
+		// from ever becoming idle
+		if ev.Type == network.ResourceTypeEventSource || ev.Type == network.ResourceTypePing {
+			return
+		}
+		t.inflight[ev.RequestID] = struct{}{}
+		t.lastActivity = time.Now()
+	case *network.EventLoadingFinished:
+		delete(t.inflight, ev.RequestID)
+		t.lastActivity = time.Now()
+	case *network.EventLoadingFailed:
+		delete(t.inflight, ev.RequestID)
+		t.lastActivity = time.Now()
+	case *page.EventFrameNavigated:
+		if ev.Frame.ParentID == "" {
+			t.inflight = make(map[network.RequestID]struct{})
+			t.lastActivity = time.Now()
+		}
+	}
+}
+
+func (t *networkTracker) isIdle(maxInflightRequests int, idleDuration time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.inflight) <= maxInflightRequests && time.Since(t.lastActivity) >= idleDuration
+}
+
+// Waits until the document of the active tab has loaded, the network is idle, and the DOM is stable.
+// Timeouts are logged rather than returned so that the agent can act on a partially loaded page.
+func (b *Browser) waitForPageToSettle(actionType trajectory.BrowserActionType) {
+	options := b.waitOptions
+	deadline := time.Now().Add(options.timeoutFor(actionType))
+	for {
+		if loaded, err := b.isPageLoaded(); errors.Is(err, ErrDialogOpen) {
+			// the page stays blocked until the dialog is closed
+			return
+		} else if err != nil {
+			log.Println("error checking if page is loaded:", err)
+		} else if loaded {
+			break
+		}
+		if time.Now().After(deadline) {
+			log.Printf("timed out waiting for page load after %s", actionType)
+			return
+		}
+		time.Sleep(waitPollInterval)
+	}
+	if !options.WaitForNetworkAndDOM {
+		return
+	}
+	if tracker := b.activeTab.network; tracker != nil {
+		for !tracker.isIdle(options.MaxInflightRequests, options.NetworkIdleDuration) {
+			if time.Now().After(deadline) {
+				log.Printf("timed out waiting for network idle after %s", actionType)
+				return
+			}
+			time.Sleep(waitPollInterval)
+		}
+	}
+	if remaining := time.Until(deadline); remaining <= 0 {
+		log.Printf("timed out waiting for dom stability after %s", actionType)
+	} else if stable, err := b.waitForDOMQuiet(options.DOMQuietDuration, remaining); err != nil {
+		log.Println("error waiting for dom stability:", err)
+	} else if !stable {
+		log.Printf("timed out waiting for dom stability after %s", actionType)
+	}
+}
+
+// Waits for a period without DOM mutations. Returns false if the timeout elapsed first.
+func (b *Browser) waitForDOMQuiet(quietDuration time.Duration, timeout time.Duration) (bool, error) {
+	js := fmt.Sprintf(`function waitForDOMQuiet(quietMs, timeoutMs) {
+	return new Promise(resolve => {
+		let quietTimer = null;
+		let timeoutTimer = null;
+		const observer = new MutationObserver(() => {
+			clearTimeout(quietTimer);
+			quietTimer = setTimeout(finish(true), quietMs);
+		});
+		const finish = result => () => {
+			observer.disconnect();
+			clearTimeout(quietTimer);
+			clearTimeout(timeoutTimer);
+			resolve(result);
+		};
+		observer.observe(document, {subtree: true, childList: true, attributes: true, characterData: true});
+		quietTimer = setTimeout(finish(true), quietMs);
+		timeoutTimer = setTimeout(finish(false), timeoutMs);
+	});
+}
+waitForDOMQuiet(%d, %d);`, quietDuration.Milliseconds(), timeout.Milliseconds())
+	var stable bool
+	if err := b.run(chromedp.Evaluate(js, &stable, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	})); err != nil {
+		return false, err
+	}
+	return stable, nil
+}
+
+// Waits until the text is visible on the page or an element matches the css selector. Returns false if the
+// timeout elapsed first.
+func (b *Browser) WaitFor(text string, selector string, timeout time.Duration) (bool, error) {
+	if text == "" && selector == "" {
+		return false, errors.New("either text or selector must be given")
+	}
+	if timeout <= 0 {
+		timeout = DefaultWaitForTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for i := 0; ; i++ {
+		found, err := b.isWaitConditionMet(text, selector)
+		if errors.Is(err, ErrDialogOpen) {
+			return false, err
+		} else if err != nil {
+			// the first check runs against a settled page, so an error is most likely an invalid selector;
+			// later errors can be caused by navigations and are retried
+			if i == 0 {
+				return false, fmt.Errorf("error checking wait condition: %w", err)
+			}
+			log.Println("error checking wait condition:", err)
+		} else if found {
+			b.waitForPageToSettle(trajectory.BrowserActionTypeWaitFor)
+			return true, b.updateDisplay()
+		}
+		if time.Now().After(deadline) {
+			return false, b.updateDisplay()
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+func (b *Browser) isWaitConditionMet(text string, selector string) (bool, error) {
+	js := deepQueryJS + fmt.Sprintf(`function isWaitConditionMet(text, selector) {
+	if (selector !== '' && deepQuerySelector(selector) !== null) {
+		return true;
+	}
+	if (text !== '') {
+		const needle = text.toLowerCase();
+		return collectQueryRoots(document, []).some(root => {
+			const container = root.body !== undefined ? root.body : root;
+			const haystack = container ? (container.innerText !== undefined ? container.innerText : container.textContent) : '';
+			return (haystack || '').toLowerCase().includes(needle);
+		});
+	}
+	return false;
+}
+isWaitConditionMet(%s, %s);`, quoteJS(text), quoteJS(selector))
+	var found bool
+	if err := b.run(chromedp.Evaluate(js, &found)); err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+func describeWaitCondition(text string, selector string) string {
+	conditions := []string{}
+	if text != "" {
+		conditions = append(conditions, fmt.Sprintf("text \"%s\"", text))
+	}
+	if selector != "" {
+		conditions = append(conditions, fmt.Sprintf("selector \"%s\"", selector))
+	}
+	return strings.Join(conditions, " or ")
+}
@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"testing"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestNetworkTrackerInflight(t *testing.T) {
+	request := func(id string, typ network.ResourceType) *network.EventRequestWillBeSent {
+		return &network.EventRequestWillBeSent{RequestID: network.RequestID(id), Type: typ}
+	}
+	tests := []struct {
+		name   string
+		events []interface{}
+		want   int
+	}{
+		{"no requests", nil, 0},
+		{"pending requests", []interface{}{request("1", network.ResourceTypeXHR), request("2", network.ResourceTypeScript)}, 2},
+		{"finished and failed requests", []interface{}{
+			request("1", network.ResourceTypeXHR),
+			request("2", network.ResourceTypeFetch),
+			&network.EventLoadingFinished{RequestID: "1"},
+			&network.EventLoadingFailed{RequestID: "2"},
+		}, 0},
+		{"event streams and beacons", []interface{}{request("1", network.ResourceTypeEventSource), request("2", network.ResourceTypePing)}, 0},
+		{"main frame navigation", []interface{}{
+			request("1", network.ResourceTypeXHR),
+			&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "main"}},
+			request("2", network.ResourceTypeScript),
+		}, 1},
+		{"child frame navigation", []interface{}{
+			request("1", network.ResourceTypeXHR),
+			&page.EventFrameNavigated{Frame: &cdp.Frame{ID: "child", ParentID: "main"}},
+		}, 1},
+	}
+	for _, test := range tests {
+		tracker := newNetworkTracker()
+		for _, ev := range test.events {
+			tracker.handleEvent(ev)
+		}
+		if got := len(tracker.inflight); got != test.want {
+			t.Errorf("%s: %d requests in flight, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNetworkTrackerIsIdle(t *testing.T) {
+	tracker := newNetworkTracker()
+	for _, id := range []network.RequestID{"1", "2", "3"} {
+		tracker.handleEvent(&network.EventRequestWillBeSent{RequestID: id, Type: network.ResourceTypeXHR})
+	}
+	tests := []struct {
+		maxInflightRequests int
+		want                bool
+	}{
+		{0, false},
+		{2, false},
+		{3, true},
+	}
+	for _, test := range tests {
+		if got := tracker.isIdle(test.maxInflightRequests, 0); got != test.want {
+			t.Errorf("isIdle(%d, 0) = %v, want %v", test.maxInflightRequests, got, test.want)
+		}
+	}
+}
@@ -14,15 +14,46 @@ import (
 	"io"
 	"log"
 	"os"
+	"path"
+	"strconv"
+	"strings"
 )
 
+// The number of requests that the network command prints if it is not given a number.
+const defaultNetworkEntries = 20
+
 func main() {
 	ctx := context.Background()
+	launchConfigPath := flag.String("launch-config", "", "a json file with the launch config of the browser; the flags below override its fields")
 	runHeadful := flag.Bool("headful", false, "run the browser in non-headless mode")
+	chromePath := flag.String("chrome-path", "", "the path of the chrome binary to launch")
+	userDataDir := flag.String("user-data-dir", "", "the profile directory of the browser, which keeps cookies and storage between runs")
+	proxy := flag.String("proxy", "", "the proxy server for all requests, such as \"http://localhost:8080\"")
+	userAgent := flag.String("user-agent", browser.DefaultUserAgent, "the user agent of the browser")
+	windowSize := flag.String("window-size", "", "the size of the browser window, such as \"1280x800\"")
+	locale := flag.String("locale", "", "the language of the browser, such as \"en-US\"")
+	timezone := flag.String("timezone", "", "the time zone of the browser, such as \"America/New_York\"")
+	chromeFlags := flag.String("chrome-flags", "", "a comma separated list of extra chrome flags, such as \"disable-gpu,force-device-scale-factor=2\"")
+	extensions := flag.String("extensions", "", "a comma separated list of directories of unpacked extensions to load in headful mode")
 	logPath := flag.String("log-path", "out", "the path to write the trajectory and browser display to")
-	initialURL := flag.String("url", "https://www.google.com", "the initial url to visit")
+	initialURL := flag.String("url", "https://www.google.com", "the initial url to visit; defaults to the location of the selected tab when a remote browser is used")
+	remoteDebuggingURL := flag.String("remote-debugging-url", "", "the devtools url of an already running chrome to use instead of launching one, such as \"http://localhost:9222\"")
+	remoteTarget := flag.String("target", "", "the id, or part of the url or title, of the tab of the remote browser to start in; the tabs are listed to choose from if it is not set")
 	actorStrategy := flag.String("actor-strategy", "base", "the actor strategy to use; one of [\"base\", \"reflexion\"]")
-	afforderStrategy := flag.String("afforder-strategy", "function", "the afforder strategy to use; one of [\"function\", filter\"]")
+	afforderStrategy := flag.String("afforder-strategy", "function", "the afforder strategy to use; one of [\"function\", \"filter\", \"function_ax\"]")
+	renderMode := flag.String("render-mode", "full", "the render mode to use; one of [\"full\", \"viewport\"]")
+	screenshotMode := flag.String("screenshots", "none", "whether to capture a screenshot after every action; one of [\"none\", \"viewport\", \"full_page\"]")
+	dialogPolicy := flag.String("dialog-policy", "none", "how javascript dialogs are handled when they open; one of [\"none\", \"accept\", \"dismiss\"], where \"none\" leaves them for the agent")
+	storageStatePath := flag.String("storage-state", "", "a storage state file to load cookies and storage from at startup; \"save state\" writes to it, or to the log path if it is not set")
+	uploadDirs := flag.String("upload-dirs", "", "a comma separated list of local directories that files can be uploaded from")
+	blockList := flag.String("block-list", "", "a file of domains and url patterns to block requests to, such as ads and trackers")
+	urlCanonicalization := flag.String("url-canonicalization", "syntax", "how urls are completed before they are visited; one of [\"syntax\", \"probe\"], where \"probe\" sends requests to follow redirects and prefer www. hosts")
+	allowedDomains := flag.String("allowed-domains", "", "a comma separated list of domain patterns, such as \"example.com\" or \"*.example.com\", that the browser may visit; any domain may be visited if it is not set")
+	deniedDomains := flag.String("denied-domains", "", "a comma separated list of domain patterns that the browser may not visit")
+	allowedSchemes := flag.String("allowed-schemes", "", "a comma separated list of url schemes, such as \"https\", that the browser may visit; any scheme may be visited if it is not set")
+	deniedSchemes := flag.String("denied-schemes", "", "a comma separated list of url schemes that the browser may not visit")
+	blockHeavyResources := flag.Bool("block-heavy-resources", false, "whether to block images, fonts, and media for faster runs")
+	emulation := flag.String("emulation", "", "the device and region to emulate; a built-in profile such as \"iphone-14\" or a json profile file")
 	verbose := flag.Bool("verbose", false, "whether to print verbose debug logs")
 	flag.Parse()
 
@@ -31,11 +62,86 @@ func main() {
 		log.SetOutput(io.Discard)
 	}
 
-	browserOptions := []browser.BrowserOption{
-		browser.BrowserOptionAttemptToDisableAutomationMessage,
+	launchConfig := browser.DefaultLaunchConfig()
+	if *launchConfigPath != "" {
+		config, err := browser.LoadLaunchConfig(*launchConfigPath)
+		if err != nil {
+			panic(fmt.Errorf("failed to load launch config: %w", err))
+		}
+		launchConfig = config
+	}
+	if isFlagSet("headful") {
+		launchConfig.Headful = *runHeadful
+	}
+	if isFlagSet("chrome-path") {
+		launchConfig.ExecPath = *chromePath
+	}
+	if isFlagSet("user-data-dir") {
+		launchConfig.UserDataDir = *userDataDir
+	}
+	if isFlagSet("proxy") {
+		launchConfig.Proxy = *proxy
+	}
+	if isFlagSet("user-agent") {
+		launchConfig.UserAgent = *userAgent
+	}
+	if isFlagSet("window-size") {
+		size, err := parseWindowSize(*windowSize)
+		if err != nil {
+			panic(err)
+		}
+		launchConfig.Window = size
+	}
+	if isFlagSet("locale") {
+		launchConfig.Locale = *locale
+	}
+	if isFlagSet("timezone") {
+		launchConfig.Timezone = *timezone
+	}
+	if isFlagSet("chrome-flags") {
+		launchConfig.ExtraFlags = splitList(*chromeFlags)
+	}
+	if isFlagSet("extensions") {
+		launchConfig.Extensions = splitList(*extensions)
+	}
+	if isFlagSet("block-list") {
+		launchConfig.BlockList = *blockList
+	}
+	if isFlagSet("block-heavy-resources") {
+		launchConfig.BlockHeavyResources = *blockHeavyResources
+	}
+
+	uploadDirectories := splitList(*uploadDirs)
+
+	var navigationPolicy *browser.NavigationPolicy
+	if *allowedDomains != "" || *deniedDomains != "" || *allowedSchemes != "" || *deniedSchemes != "" {
+		navigationPolicy = &browser.NavigationPolicy{
+			AllowedDomains: splitList(*allowedDomains),
+			DeniedDomains:  splitList(*deniedDomains),
+			AllowedSchemes: splitList(*allowedSchemes),
+			DeniedSchemes:  splitList(*deniedSchemes),
+		}
+	}
+
+	var emulationProfile *browser.EmulationProfile
+	if *emulation != "" {
+		profile, err := loadEmulationProfile(*emulation)
+		if err != nil {
+			panic(err)
+		}
+		emulationProfile = profile
 	}
-	if *runHeadful {
-		browserOptions = append(browserOptions, browser.BrowserOptionHeadful)
+
+	var remoteTargetSelector browser.TargetSelector
+	if *remoteDebuggingURL != "" {
+		selected, err := selectRemoteTarget(ctx, *remoteDebuggingURL, *remoteTarget)
+		if err != nil {
+			panic(fmt.Errorf("failed to select a tab of the remote browser: %w", err))
+		}
+		remoteTargetSelector = browser.TargetSelectorByQuery(selected.ID)
+		if !isFlagSet("url") {
+			*initialURL = selected.URL
+		}
 	}
 
 	openaiAPIKey := os.Getenv("OPENAI_API_KEY")
@@ -52,11 +158,21 @@ func main() {
 		*afforderStrategy = string(afforder.DefaultAfforderStrategyID)
 	}
 	runner, err := finiterunner.NewFiniteRunnerFromInitialPage(ctx, *initialURL, apiKeys, &finiterunner.Options{
-		MaxNumSteps:        5,
-		BrowserOptions:     browserOptions,
-		LogPath:            *logPath,
-		ActorStrategyID:    actor.ActorStrategyID(*actorStrategy),
-		AfforderStrategyID: afforder.AfforderStrategyID(*afforderStrategy),
+		MaxNumSteps:          5,
+		LaunchConfig:         launchConfig,
+		RemoteDebuggingURL:   *remoteDebuggingURL,
+		RemoteTargetSelector: remoteTargetSelector,
+		RenderMode:           browser.RenderMode(*renderMode),
+		ScreenshotMode:       browser.ScreenshotMode(*screenshotMode),
+		DialogPolicy:         browser.DialogPolicy(*dialogPolicy),
+		EmulationProfile:     emulationProfile,
+		NavigationPolicy:     navigationPolicy,
+		URLCanonicalization:  browser.URLCanonicalizationMode(*urlCanonicalization),
+		StorageStatePath:     *storageStatePath,
+		UploadDirectories:    uploadDirectories,
+		LogPath:              *logPath,
+		ActorStrategyID:      actor.ActorStrategyID(*actorStrategy),
+		AfforderStrategyID:   afforder.AfforderStrategyID(*afforderStrategy),
 	})
 	if err != nil {
 		panic(fmt.Errorf("failed to create runner: %w", err))
@@ -71,6 +187,39 @@ func main() {
 ScannerLoop:
 	for scanner.Scan() {
 		userMessageText := scanner.Text()
+		if profileName, ok := strings.CutPrefix(userMessageText, "emulate "); ok {
+			var profile *browser.EmulationProfile
+			var err error
+			if profileName = strings.TrimSpace(profileName); profileName != "off" {
+				profile, err = loadEmulationProfile(profileName)
+			}
+			if err == nil {
+				err = runner.SetEmulationProfile(profile)
+			}
+			if err != nil {
+				printx.PrintInColor(printx.ColorYellow, fmt.Sprintf("Failed to set the emulation profile: %s", err.Error()))
+			} else if profile == nil {
+				printx.PrintInColor(printx.ColorGray, "Stopped emulating.")
+			} else {
+				printx.PrintInColor(printx.ColorGray, "Emulating "+profile.String()+".")
+			}
+			fmt.Print("user: ")
+			continue ScannerLoop
+		}
+		if userMessageText == "network" || strings.HasPrefix(userMessageText, "network ") {
+			n := defaultNetworkEntries
+			if arg := strings.TrimSpace(strings.TrimPrefix(userMessageText, "network")); arg != "" {
+				var err error
+				if n, err = strconv.Atoi(arg); err != nil || n <= 0 {
+					printx.PrintInColor(printx.ColorYellow, fmt.Sprintf("Invalid number of requests: %s", arg))
+					fmt.Print("user: ")
+					continue ScannerLoop
+				}
+			}
+			runner.DisplayNetworkLog(n)
+			fmt.Print("user: ")
+			continue ScannerLoop
+		}
 		switch userMessageText {
 		case "":
 			fmt.Print("user: ")
@@ -100,8 +249,20 @@ ScannerLoop:
 			printx.PrintInColor(printx.ColorGray, "Logged the current state to "+*logPath+".")
 			fmt.Print("user: ")
 			continue ScannerLoop
+		case "save state":
+			statePath := *storageStatePath
+			if statePath == "" {
+				statePath = path.Join(*logPath, "storage_state.json")
+			}
+			if err := runner.SaveState(statePath); err != nil {
+				printx.PrintInColor(printx.ColorYellow, fmt.Sprintf("Failed to save the browser state: %s", err.Error()))
+			} else {
+				printx.PrintInColor(printx.ColorGray, "Saved the cookies and storage of the browser to "+statePath+".")
+			}
+			fmt.Print("user: ")
+			continue ScannerLoop
 		case "help":
-			printx.PrintInColor(printx.ColorGray, "This interface is simple - just type natural language. For example, to navigate to google, type \"go to google.com\".\nTo log the current state, type \"log\".\nTo exit gracefully, type \"exit\".")
+			printx.PrintInColor(printx.ColorGray, "This interface is simple - just type natural language. For example, to navigate to google, type \"go to google.com\".\nTo log the current state, type \"log\".\nTo save the cookies and storage of the browser, type \"save state\".\nTo emulate a device or region, type \"emulate <profile>\" with a built-in profile such as \"iphone-14\" or a json profile file, or \"emulate off\" to stop.\nTo view the last requests of the browser, type \"network\" or \"network <N>\".\nTo exit gracefully, type \"exit\".")
 			fmt.Print("user: ")
 			continue ScannerLoop
 		default:
@@ -127,3 +288,68 @@ ScannerLoop:
 		}
 	}
 }
+
+// Splits a comma separated list, dropping empty items.
+func splitList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Selects the tab of the remote browser that matches the query, or asks the user to choose one if the query is
+// empty and there is more than one tab.
+func selectRemoteTarget(ctx context.Context, devtoolsURL string, query string) (*browser.RemoteTarget, error) {
+	targets, err := browser.ListRemoteTargets(ctx, devtoolsURL)
+	if err != nil {
+		return nil, err
+	} else if query != "" || len(targets) < 2 {
+		return browser.TargetSelectorByQuery(query)(targets)
+	}
+	printx.PrintStandardHeader("TABS")
+	fmt.Println()
+	for i, t := range targets {
+		fmt.Printf("%d: %s - %s\n", i+1, t.Title, t.URL)
+	}
+	fmt.Print("choose a tab: ")
+	var choice int
+	if _, err := fmt.Scanln(&choice); err != nil {
+		return nil, fmt.Errorf("error reading choice: %w", err)
+	} else if choice < 1 || choice > len(targets) {
+		return nil, fmt.Errorf("invalid choice: %d", choice)
+	}
+	return targets[choice-1], nil
+}
+
+// Parses a window size such as "1280x800".
+func parseWindowSize(size string) (*browser.WindowSize, error) {
+	if size == "" {
+		return nil, nil
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(size, "%dx%d", &width, &height); err != nil {
+		return nil, fmt.Errorf("invalid window size %q: %w", size, err)
+	}
+	return &browser.WindowSize{Width: width, Height: height}, nil
+}
+
+// Loads an emulation profile from a json file, or else the built-in profile with the name.
+func loadEmulationProfile(nameOrPath string) (*browser.EmulationProfile, error) {
+	if _, err := os.Stat(nameOrPath); err == nil {
+		return browser.LoadEmulationProfile(nameOrPath)
+	}
+	return browser.EmulationProfileByName(nameOrPath)
+}
@@ -26,13 +26,39 @@ type FiniteRunner struct {
 	maxNumSteps int
 	trajectory  *trajectory.Trajectory
 	logPath     string
+	// the screenshots that were written to the log by this runner
+	loggedScreenshots map[*trajectory.Screenshot]bool
+	// the revision of the network log that was last written to the log
+	loggedNetworkRevision int
 }
 
 const DefaultMaxNumSteps = 5
 
 type Options struct {
-	MaxNumSteps        int
-	BrowserOptions     []browser.BrowserOption
+	MaxNumSteps int
+	// How the browser is launched; defaults to browser.DefaultLaunchConfig.
+	LaunchConfig *browser.LaunchConfig
+	// The DevTools url of an already running browser to drive instead of launching one, such as
+	// "http://localhost:9222".
+	RemoteDebuggingURL string
+	// Picks the tab of the remote browser to start in; defaults to its first tab.
+	RemoteTargetSelector browser.TargetSelector
+	RenderMode           browser.RenderMode
+	WaitOptions          *browser.WaitOptions
+	ScreenshotMode       browser.ScreenshotMode
+	DialogPolicy         browser.DialogPolicy
+	// The device and region to emulate from the start; nothing is emulated if it is nil.
+	EmulationProfile *browser.EmulationProfile
+	// The domains and url schemes that the browser may visit; any page may be visited if it is nil.
+	NavigationPolicy *browser.NavigationPolicy
+	// How urls are canonicalized before they are visited; defaults to browser.URLCanonicalizationModeSyntax.
+	URLCanonicalization browser.URLCanonicalizationMode
+	// The local directories that files can be uploaded from.
+	UploadDirectories []string
+	// The directory that downloads and other artifacts are saved to; defaults to the artifacts directory in the log path.
+	ArtifactsPath string
+	// A storage state file to load cookies and storage from before the initial page is visited.
+	StorageStatePath   string
 	LogPath            string
 	ActorStrategyID    actor.ActorStrategyID
 	AfforderStrategyID afforder.AfforderStrategyID
@@ -40,12 +66,33 @@ type Options struct {
 
 const DefaultLogPath = "log"
 
+// Launches a browser, or connects to the remote browser of the options if one is set.
+func newBrowser(ctx context.Context, policy *browser.NavigationPolicy, launchConfig *browser.LaunchConfig, options *Options) (*browser.Browser, error) {
+	if options == nil || options.RemoteDebuggingURL == "" {
+		b, err := browser.NewBrowser(ctx, policy, launchConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to launch browser: %w", err)
+		}
+		return b, nil
+	}
+	b, err := browser.NewBrowserFromRemoteWithOptions(ctx, options.RemoteDebuggingURL, &browser.RemoteOptions{
+		TargetSelector:   options.RemoteTargetSelector,
+		NavigationPolicy: policy,
+		LaunchConfig:     launchConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote browser: %w", err)
+	}
+	return b, nil
+}
+
 func NewFiniteRunnerFromInitialPage(ctx context.Context, url string, apiKeys map[string]string, options *Options) (runner.Runner, error) {
 	maxNumSteps := DefaultMaxNumSteps
 	logPath := DefaultLogPath
-	browserOptions := []browser.BrowserOption{}
+	launchConfig := browser.DefaultLaunchConfig()
 	actorStrategyID := actor.DefaultActorStrategyID
 	afforderStrategyID := afforder.DefaultAfforderStrategyID
+	var navigationPolicy *browser.NavigationPolicy
 	if options != nil {
 		if options.MaxNumSteps > 0 {
 			maxNumSteps = options.MaxNumSteps
@@ -53,9 +100,10 @@ func NewFiniteRunnerFromInitialPage(ctx context.Context, url string, apiKeys map
 		if options.LogPath != "" {
 			logPath = options.LogPath
 		}
-		if options.BrowserOptions != nil {
-			browserOptions = options.BrowserOptions
+		if options.LaunchConfig != nil {
+			launchConfig = options.LaunchConfig
 		}
+		navigationPolicy = options.NavigationPolicy
 		if options.ActorStrategyID != "" {
 			actorStrategyID = options.ActorStrategyID
 		}
@@ -76,7 +124,55 @@ func NewFiniteRunnerFromInitialPage(ctx context.Context, url string, apiKeys map
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize actor: %w", err)
 		}
-		browser := browser.NewBrowser(ctx, browserOptions...)
+		browser, err := newBrowser(ctx, navigationPolicy, launchConfig, options)
+		if err != nil {
+			return nil, err
+		}
+		if options != nil && options.RenderMode != "" {
+			if err := browser.SetRenderMode(options.RenderMode); err != nil {
+				return nil, fmt.Errorf("failed to set render mode: %w", err)
+			}
+		}
+		if options != nil && options.WaitOptions != nil {
+			browser.SetWaitOptions(options.WaitOptions)
+		}
+		if options != nil && options.ScreenshotMode != "" {
+			if err := browser.SetScreenshotMode(options.ScreenshotMode); err != nil {
+				return nil, fmt.Errorf("failed to set screenshot mode: %w", err)
+			}
+		}
+		if options != nil && options.URLCanonicalization != "" {
+			if err := browser.SetURLCanonicalizationMode(options.URLCanonicalization); err != nil {
+				return nil, fmt.Errorf("failed to set url canonicalization mode: %w", err)
+			}
+		}
+		if options != nil && options.EmulationProfile != nil {
+			if err := browser.SetEmulationProfile(options.EmulationProfile); err != nil {
+				return nil, fmt.Errorf("failed to set emulation profile: %w", err)
+			}
+		}
+		if options != nil && options.DialogPolicy != "" {
+			if err := browser.SetDialogPolicy(options.DialogPolicy); err != nil {
+				return nil, fmt.Errorf("failed to set dialog policy: %w", err)
+			}
+		}
+		artifactsPath := path.Join(logPath, "artifacts")
+		if options != nil && options.ArtifactsPath != "" {
+			artifactsPath = options.ArtifactsPath
+		}
+		if err := browser.SetArtifactsDirectory(artifactsPath); err != nil {
+			return nil, fmt.Errorf("failed to set artifacts directory: %w", err)
+		}
+		if options != nil && len(options.UploadDirectories) > 0 {
+			if err := browser.SetUploadDirectories(options.UploadDirectories); err != nil {
+				return nil, fmt.Errorf("failed to set upload directories: %w", err)
+			}
+		}
+		if options != nil && options.StorageStatePath != "" {
+			if err := browser.LoadState(options.StorageStatePath); err != nil {
+				return nil, fmt.Errorf("failed to load storage state: %w", err)
+			}
+		}
 		initialAction := trajectory.NewBrowserNavigateAction(url)
 		observation, err := browser.AcceptAction(initialAction.(*trajectory.BrowserAction))
 		if err != nil {
@@ -90,6 +186,10 @@ func NewFiniteRunnerFromInitialPage(ctx context.Context, url string, apiKeys map
 				initialObservation,
 			},
 		}
+		trajectory.AddItems(newDownloadItems(browser))
+		if screenshot := newScreenshotItem(browser, trajectory); screenshot != nil {
+			trajectory.AddItem(screenshot)
+		}
 		printx.PrintStandardHeader("CONFIGURATION")
 		fmt.Printf("\nInitializing a finite runner with the following configuration:\n- Maximum number steps per turn: %d\n- Actor strategy: %s\n- Log path: %s\n", maxNumSteps, actorStrategyID, logPath)
 		return &FiniteRunner{
@@ -99,6 +199,8 @@ func NewFiniteRunnerFromInitialPage(ctx context.Context, url string, apiKeys map
 			maxNumSteps: maxNumSteps,
 			trajectory:  trajectory,
 			logPath:     logPath,
+			// the network log is written by the first call to Log, even if it is empty
+			loggedNetworkRevision: -1,
 		}, nil
 	}
 }
@@ -119,6 +221,13 @@ func (r *FiniteRunner) Run() error {
 			browserDisplay := r.browser.GetDisplay()
 			r.trajectory.AddItem(trajectory.NewDebugRenderedDisplay(trajectory.DebugDisplayTypeBrowser, browserDisplay.MD))
 			r.trajectory.AddItem(trajectory.NewBrowserObservation(observation))
+			r.trajectory.AddItems(newDownloadItems(r.browser))
+			if extraction := newExtractionItem(r.browser, r.trajectory); extraction != nil {
+				r.trajectory.AddItem(extraction)
+			}
+			if screenshot := newScreenshotItem(r.browser, r.trajectory); screenshot != nil {
+				r.trajectory.AddItem(screenshot)
+			}
 		}
 	}
 	r.trajectory.AddItem(trajectory.NewErrorMaxNumStepsReached(r.maxNumSteps))
@@ -161,6 +270,15 @@ func (r *FiniteRunner) RunAndStream() (<-chan *trajectory.TrajectoryStreamEvent,
 				browserDisplay := r.browser.GetDisplay()
 				addAndSendTrajectoryItem(trajectory.NewDebugRenderedDisplay(trajectory.DebugDisplayTypeBrowser, browserDisplay.MD))
 				addAndSendTrajectoryItem(trajectory.NewBrowserObservation(observation))
+				for _, item := range newDownloadItems(r.browser) {
+					addAndSendTrajectoryItem(item)
+				}
+				if extraction := newExtractionItem(r.browser, r.trajectory); extraction != nil {
+					addAndSendTrajectoryItem(extraction)
+				}
+				if screenshot := newScreenshotItem(r.browser, r.trajectory); screenshot != nil {
+					addAndSendTrajectoryItem(screenshot)
+				}
 			}
 		}
 		addAndSendTrajectoryItem(trajectory.NewErrorMaxNumStepsReached(r.maxNumSteps))
@@ -168,6 +286,44 @@ func (r *FiniteRunner) RunAndStream() (<-chan *trajectory.TrajectoryStreamEvent,
 	return stream, nil
 }
 
+// Returns an observation for each download that completed since the last action.
+func newDownloadItems(br *browser.Browser) []trajectory.TrajectoryItem {
+	items := []trajectory.TrajectoryItem{}
+	for _, download := range br.TakeCompletedDownloads() {
+		items = append(items, trajectory.NewDownloadObservation(download.URL, download.FileName, download.Path, download.Size, download.MIMEType))
+	}
+	return items
+}
+
+// Returns a trajectory item for the screenshot that the browser captured after its last action, if any.
+func newScreenshotItem(br *browser.Browser, traj *trajectory.Trajectory) trajectory.TrajectoryItem {
+	screenshot := br.GetLastScreenshot()
+	if screenshot == nil {
+		return nil
+	}
+	return trajectory.NewScreenshot(countSteps(traj), screenshot.Data, screenshot.FullPage, screenshot.Location)
+}
+
+// Returns a trajectory item with the rows of the last action if it was an extraction.
+func newExtractionItem(br *browser.Browser, traj *trajectory.Trajectory) trajectory.TrajectoryItem {
+	extraction := br.GetLastExtraction()
+	if extraction == nil {
+		return nil
+	}
+	return trajectory.NewExtraction(countSteps(traj), extraction.Source, extraction.Location, extraction.Fields, extraction.Rows)
+}
+
+// Returns the number of browser actions in the trajectory.
+func countSteps(traj *trajectory.Trajectory) int {
+	step := 0
+	for _, item := range traj.Items {
+		if _, ok := item.(*trajectory.BrowserAction); ok {
+			step++
+		}
+	}
+	return step
+}
+
 func (r *FiniteRunner) AddItemToTrajectory(item trajectory.TrajectoryItem) {
 	r.trajectory.AddItem(item)
 }
@@ -178,6 +334,13 @@ func (r *FiniteRunner) DisplayTrajectory() {
 	}
 }
 
+// Prints the last n requests of the browser, or all requests if n is not positive.
+func (r *FiniteRunner) DisplayNetworkLog(n int) {
+	for _, entry := range r.browser.GetLastNetworkEntries(n) {
+		fmt.Println(entry.String())
+	}
+}
+
 func (r *FiniteRunner) Log() error {
 	if _, err := os.Stat(r.logPath); os.IsNotExist(err) {
 		if err := os.MkdirAll(r.logPath, 0755); err != nil {
@@ -195,11 +358,100 @@ func (r *FiniteRunner) Log() error {
 		return fmt.Errorf("failed to write display markdown to file: %w", err)
 	} else if err := io.WriteStringToFile(path.Join(r.logPath, "display.html"), gohtml.Format(browserDisplay.HTML)); err != nil {
 		return fmt.Errorf("failed to write display html to file: %w", err)
+	} else if err := r.logScreenshots(); err != nil {
+		return fmt.Errorf("failed to write screenshots: %w", err)
+	} else if err := r.logConsole(); err != nil {
+		return fmt.Errorf("failed to write console log: %w", err)
+	} else if err := r.logNetwork(); err != nil {
+		return fmt.Errorf("failed to write network log: %w", err)
+	} else if err := r.logExtractions(); err != nil {
+		return fmt.Errorf("failed to write extractions: %w", err)
 	} else {
 		return nil
 	}
 }
 
+func (r *FiniteRunner) logScreenshots() error {
+	screenshotsPath := path.Join(r.logPath, "screenshots")
+	if r.loggedScreenshots == nil {
+		r.loggedScreenshots = make(map[*trajectory.Screenshot]bool)
+	}
+	for _, item := range r.trajectory.Items {
+		screenshot, ok := item.(*trajectory.Screenshot)
+		if !ok {
+			continue
+		}
+		if err := os.MkdirAll(screenshotsPath, 0755); err != nil {
+			return fmt.Errorf("failed to create screenshots directory: %w", err)
+		}
+		// screenshots do not change once they are captured, so each is only written once by this runner; files
+		// that were left in a reused log directory by an earlier run are overwritten
+		if r.loggedScreenshots[screenshot] {
+			continue
+		}
+		if err := io.WriteBytesToFile(path.Join(screenshotsPath, screenshot.FileName()), screenshot.Data); err != nil {
+			return fmt.Errorf("failed to write screenshot %s: %w", screenshot.FileName(), err)
+		}
+		r.loggedScreenshots[screenshot] = true
+	}
+	return nil
+}
+
+// Writes the rows of each extraction as JSON and CSV files to the extractions directory.
+func (r *FiniteRunner) logExtractions() error {
+	extractionsPath := path.Join(r.logPath, "extractions")
+	for _, item := range r.trajectory.Items {
+		extraction, ok := item.(*trajectory.Extraction)
+		if !ok {
+			continue
+		}
+		if err := os.MkdirAll(extractionsPath, 0755); err != nil {
+			return fmt.Errorf("failed to create extractions directory: %w", err)
+		}
+		if data, err := extraction.JSON(); err != nil {
+			return fmt.Errorf("failed to encode extraction %s as json: %w", extraction.BaseName(), err)
+		} else if err := io.WriteBytesToFile(path.Join(extractionsPath, extraction.BaseName()+".json"), data); err != nil {
+			return fmt.Errorf("failed to write extraction %s: %w", extraction.BaseName(), err)
+		}
+		if data, err := extraction.CSV(); err != nil {
+			return fmt.Errorf("failed to encode extraction %s as csv: %w", extraction.BaseName(), err)
+		} else if err := io.WriteBytesToFile(path.Join(extractionsPath, extraction.BaseName()+".csv"), data); err != nil {
+			return fmt.Errorf("failed to write extraction %s: %w", extraction.BaseName(), err)
+		}
+	}
+	return nil
+}
+
+// Writes the console messages and exceptions of all pages of the session to console.log.
+func (r *FiniteRunner) logConsole() error {
+	messages := r.browser.GetConsoleLog()
+	lines := []string{}
+	if dropped := r.browser.GetDroppedConsoleMessageCount(); dropped > 0 {
+		lines = append(lines, fmt.Sprintf("(%d older messages were dropped because the console log is full)", dropped))
+	}
+	for _, message := range messages {
+		lines = append(lines, message.String())
+	}
+	return io.WriteStringToFile(path.Join(r.logPath, "console.log"), strings.Join(lines, "\n"))
+}
+
+// Writes the requests of all pages of the session to network.har. The export includes the recorded response
+// bodies, so it is skipped if the network log has not changed since it was last written.
+func (r *FiniteRunner) logNetwork() error {
+	revision := r.browser.GetNetworkLogRevision()
+	if revision == r.loggedNetworkRevision {
+		return nil
+	}
+	har, err := r.browser.ExportHAR()
+	if err != nil {
+		return err
+	} else if err := io.WriteBytesToFile(path.Join(r.logPath, "network.har"), har); err != nil {
+		return err
+	}
+	r.loggedNetworkRevision = revision
+	return nil
+}
+
 func (r *FiniteRunner) RunHeadful() error {
 	return r.browser.RunHeadful(r.ctx)
 }
@@ -208,6 +460,14 @@ func (r *FiniteRunner) RunHeadless() error {
 	return r.browser.RunHeadless(r.ctx)
 }
 
+func (r *FiniteRunner) SetEmulationProfile(profile *browser.EmulationProfile) error {
+	return r.browser.SetEmulationProfile(profile)
+}
+
+func (r *FiniteRunner) SaveState(path string) error {
+	return r.browser.SaveState(path)
+}
+
 func (r *FiniteRunner) Terminate() {
 	r.browser.Cancel()
 }
@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"collaborativebrowser/browser"
 	"collaborativebrowser/trajectory"
 )
 
@@ -9,8 +10,11 @@ type Runner interface {
 	RunAndStream() (<-chan *trajectory.TrajectoryStreamEvent, error)
 	AddItemToTrajectory(item trajectory.TrajectoryItem)
 	DisplayTrajectory()
+	DisplayNetworkLog(n int)
 	RunHeadful() error
 	RunHeadless() error
+	SetEmulationProfile(profile *browser.EmulationProfile) error
+	SaveState(path string) error
 	Log() error
 	Terminate()
 }
@@ -3,6 +3,7 @@ package trajectory
 import (
 	"collaborativebrowser/browser/virtualid"
 	"fmt"
+	"strings"
 )
 
 type BrowserAction struct {
@@ -10,12 +11,48 @@ type BrowserAction struct {
 	ID     virtualid.VirtualID `json:"id"`
 	Render bool                `json:"render"`
 
-	// for send_keys
-	Text string `json:"text"`
+	// for send_keys, and the prompt text for accept_dialog
+	Text   string `json:"text"`
+	Append bool   `json:"append"`
+
+	// for press_key
+	Key string `json:"key"`
 
 	// for navigate
 	URL string `json:"url"`
 
+	// for scroll
+	Direction string `json:"direction"`
+
+	// for select_option
+	Option string `json:"option"`
+
+	// for upload_file
+	Files []string `json:"files"`
+
+	// for switch_tab and close_tab
+	TabID string `json:"tab_id"`
+
+	// for wait_for, which also uses Text
+	Selector       string  `json:"selector"`
+	TimeoutSeconds float64 `json:"timeout_seconds"`
+
+	// for inspect_network
+	URLPattern string `json:"url_pattern"`
+	MaxTokens  int    `json:"max_tokens"`
+
+	// for extract
+	Fields []string `json:"fields"`
+	Schema string   `json:"schema"`
+
+	// for drag
+	FromID virtualid.VirtualID `json:"from_id"`
+	ToID   virtualid.VirtualID `json:"to_id"`
+
+	// for click_at, in css pixels from the top left corner of the viewport
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+
 	// for task_complete or task_not_possible
 	Reason string `json:"reason"`
 
@@ -27,7 +64,26 @@ type BrowserActionType string
 const (
 	BrowserActionTypeClick           BrowserActionType = "click"
 	BrowserActionTypeSendKeys        BrowserActionType = "send_keys"
+	BrowserActionTypePressKey        BrowserActionType = "press_key"
 	BrowserActionTypeNavigate        BrowserActionType = "navigate"
+	BrowserActionTypeGoBack          BrowserActionType = "go_back"
+	BrowserActionTypeGoForward       BrowserActionType = "go_forward"
+	BrowserActionTypeReload          BrowserActionType = "reload"
+	BrowserActionTypeScroll          BrowserActionType = "scroll"
+	BrowserActionTypeSelectOption    BrowserActionType = "select_option"
+	BrowserActionTypeToggle          BrowserActionType = "toggle"
+	BrowserActionTypeUploadFile      BrowserActionType = "upload_file"
+	BrowserActionTypeWaitFor         BrowserActionType = "wait_for"
+	BrowserActionTypeAcceptDialog    BrowserActionType = "accept_dialog"
+	BrowserActionTypeDismissDialog   BrowserActionType = "dismiss_dialog"
+	BrowserActionTypeOpenTab         BrowserActionType = "open_tab"
+	BrowserActionTypeSwitchTab       BrowserActionType = "switch_tab"
+	BrowserActionTypeCloseTab        BrowserActionType = "close_tab"
+	BrowserActionTypeInspectNetwork  BrowserActionType = "inspect_network"
+	BrowserActionTypeExtract         BrowserActionType = "extract"
+	BrowserActionTypeHover           BrowserActionType = "hover"
+	BrowserActionTypeDrag            BrowserActionType = "drag"
+	BrowserActionTypeClickAt         BrowserActionType = "click_at"
 	BrowserActionTypeTaskComplete    BrowserActionType = "task_complete"
 	BrowserActionTypeTaskNotPossible BrowserActionType = "task_not_possible"
 )
@@ -40,11 +96,21 @@ func NewBrowserClickAction(id virtualid.VirtualID) TrajectoryItem {
 	}
 }
 
-func NewBrowserSendKeysAction(id virtualid.VirtualID, text string) TrajectoryItem {
+func NewBrowserSendKeysAction(id virtualid.VirtualID, text string, shouldAppend bool) TrajectoryItem {
 	return &BrowserAction{
 		Type:   BrowserActionTypeSendKeys,
 		ID:     id,
 		Text:   text,
+		Append: shouldAppend,
+		Render: true,
+	}
+}
+
+func NewBrowserPressKeyAction(id virtualid.VirtualID, key string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypePressKey,
+		ID:     id,
+		Key:    key,
 		Render: true,
 	}
 }
@@ -57,6 +123,162 @@ func NewBrowserNavigateAction(url string) TrajectoryItem {
 	}
 }
 
+func NewBrowserGoBackAction() TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeGoBack,
+		Render: true,
+	}
+}
+
+func NewBrowserGoForwardAction() TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeGoForward,
+		Render: true,
+	}
+}
+
+func NewBrowserReloadAction() TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeReload,
+		Render: true,
+	}
+}
+
+func NewBrowserScrollAction(direction string) TrajectoryItem {
+	return &BrowserAction{
+		Type:      BrowserActionTypeScroll,
+		Direction: direction,
+		Render:    true,
+	}
+}
+
+func NewBrowserScrollToElementAction(id virtualid.VirtualID) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeScroll,
+		ID:     id,
+		Render: true,
+	}
+}
+
+func NewBrowserSelectOptionAction(id virtualid.VirtualID, option string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeSelectOption,
+		ID:     id,
+		Option: option,
+		Render: true,
+	}
+}
+
+func NewBrowserToggleAction(id virtualid.VirtualID) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeToggle,
+		ID:     id,
+		Render: true,
+	}
+}
+
+func NewBrowserUploadFileAction(id virtualid.VirtualID, files []string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeUploadFile,
+		ID:     id,
+		Files:  files,
+		Render: true,
+	}
+}
+
+func NewBrowserAcceptDialogAction(promptText string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeAcceptDialog,
+		Text:   promptText,
+		Render: true,
+	}
+}
+
+func NewBrowserDismissDialogAction() TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeDismissDialog,
+		Render: true,
+	}
+}
+
+func NewBrowserWaitForAction(text string, selector string, timeoutSeconds float64) TrajectoryItem {
+	return &BrowserAction{
+		Type:           BrowserActionTypeWaitFor,
+		Text:           text,
+		Selector:       selector,
+		TimeoutSeconds: timeoutSeconds,
+		Render:         true,
+	}
+}
+
+func NewBrowserOpenTabAction(url string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeOpenTab,
+		URL:    url,
+		Render: true,
+	}
+}
+
+func NewBrowserSwitchTabAction(tabID string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeSwitchTab,
+		TabID:  tabID,
+		Render: true,
+	}
+}
+
+func NewBrowserCloseTabAction(tabID string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeCloseTab,
+		TabID:  tabID,
+		Render: true,
+	}
+}
+
+func NewBrowserInspectNetworkAction(urlPattern string, maxTokens int) TrajectoryItem {
+	return &BrowserAction{
+		Type:       BrowserActionTypeInspectNetwork,
+		URLPattern: urlPattern,
+		MaxTokens:  maxTokens,
+		Render:     true,
+	}
+}
+
+func NewBrowserExtractAction(fields []string, schema string) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeExtract,
+		Fields: fields,
+		Schema: schema,
+		Render: true,
+	}
+}
+
+func NewBrowserHoverAction(id virtualid.VirtualID) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeHover,
+		ID:     id,
+		Render: true,
+	}
+}
+
+func NewBrowserDragAction(fromID virtualid.VirtualID, toID virtualid.VirtualID) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeDrag,
+		FromID: fromID,
+		ToID:   toID,
+		Render: true,
+	}
+}
+
+func NewBrowserClickAtAction(x float64, y float64) TrajectoryItem {
+	return &BrowserAction{
+		Type:   BrowserActionTypeClickAt,
+		X:      x,
+		Y:      y,
+		Render: true,
+	}
+}
+
 func NewBrowserTaskCompleteAction(reason string) TrajectoryItem {
 	return &BrowserAction{
 		Type:   BrowserActionTypeTaskComplete,
@@ -79,9 +301,97 @@ func (ba *BrowserAction) GetText() string {
 	case BrowserActionTypeClick:
 		text = fmt.Sprintf("%s(id=%s)", ba.Type, ba.ID)
 	case BrowserActionTypeSendKeys:
-		text = fmt.Sprintf("%s(id=%s, text=\"%s\")", ba.Type, ba.ID, ba.Text)
+		if ba.Append {
+			text = fmt.Sprintf("%s(id=%s, text=\"%s\", append=true)", ba.Type, ba.ID, ba.Text)
+		} else {
+			text = fmt.Sprintf("%s(id=%s, text=\"%s\")", ba.Type, ba.ID, ba.Text)
+		}
+	case BrowserActionTypePressKey:
+		if ba.ID != "" {
+			text = fmt.Sprintf("%s(id=%s, key=\"%s\")", ba.Type, ba.ID, ba.Key)
+		} else {
+			text = fmt.Sprintf("%s(key=\"%s\")", ba.Type, ba.Key)
+		}
 	case BrowserActionTypeNavigate:
 		text = fmt.Sprintf("%s(url=\"%s\")", ba.Type, ba.URL)
+	case BrowserActionTypeGoBack, BrowserActionTypeGoForward, BrowserActionTypeReload, BrowserActionTypeDismissDialog:
+		text = fmt.Sprintf("%s()", ba.Type)
+	case BrowserActionTypeScroll:
+		if ba.ID != "" {
+			text = fmt.Sprintf("%s(id=%s)", ba.Type, ba.ID)
+		} else {
+			text = fmt.Sprintf("%s(direction=\"%s\")", ba.Type, ba.Direction)
+		}
+	case BrowserActionTypeSelectOption:
+		text = fmt.Sprintf("%s(id=%s, option=\"%s\")", ba.Type, ba.ID, ba.Option)
+	case BrowserActionTypeToggle:
+		text = fmt.Sprintf("%s(id=%s)", ba.Type, ba.ID)
+	case BrowserActionTypeUploadFile:
+		files := make([]string, len(ba.Files))
+		for i, file := range ba.Files {
+			files[i] = fmt.Sprintf("\"%s\"", file)
+		}
+		text = fmt.Sprintf("%s(id=%s, files=[%s])", ba.Type, ba.ID, strings.Join(files, ", "))
+	case BrowserActionTypeAcceptDialog:
+		if ba.Text != "" {
+			text = fmt.Sprintf("%s(prompt_text=\"%s\")", ba.Type, ba.Text)
+		} else {
+			text = fmt.Sprintf("%s()", ba.Type)
+		}
+	case BrowserActionTypeWaitFor:
+		args := []string{}
+		if ba.Text != "" {
+			args = append(args, fmt.Sprintf("text=\"%s\"", ba.Text))
+		}
+		if ba.Selector != "" {
+			args = append(args, fmt.Sprintf("selector=\"%s\"", ba.Selector))
+		}
+		if ba.TimeoutSeconds > 0 {
+			args = append(args, fmt.Sprintf("timeout_seconds=%g", ba.TimeoutSeconds))
+		}
+		text = fmt.Sprintf("%s(%s)", ba.Type, strings.Join(args, ", "))
+	case BrowserActionTypeOpenTab:
+		if ba.URL != "" {
+			text = fmt.Sprintf("%s(url=\"%s\")", ba.Type, ba.URL)
+		} else {
+			text = fmt.Sprintf("%s()", ba.Type)
+		}
+	case BrowserActionTypeSwitchTab:
+		text = fmt.Sprintf("%s(tab_id=%s)", ba.Type, ba.TabID)
+	case BrowserActionTypeCloseTab:
+		if ba.TabID != "" {
+			text = fmt.Sprintf("%s(tab_id=%s)", ba.Type, ba.TabID)
+		} else {
+			text = fmt.Sprintf("%s()", ba.Type)
+		}
+	case BrowserActionTypeInspectNetwork:
+		args := []string{}
+		if ba.URLPattern != "" {
+			args = append(args, fmt.Sprintf("url_pattern=\"%s\"", ba.URLPattern))
+		}
+		if ba.MaxTokens > 0 {
+			args = append(args, fmt.Sprintf("max_tokens=%d", ba.MaxTokens))
+		}
+		text = fmt.Sprintf("%s(%s)", ba.Type, strings.Join(args, ", "))
+	case BrowserActionTypeExtract:
+		args := []string{}
+		if len(ba.Fields) > 0 {
+			fields := make([]string, len(ba.Fields))
+			for i, field := range ba.Fields {
+				fields[i] = fmt.Sprintf("\"%s\"", field)
+			}
+			args = append(args, fmt.Sprintf("fields=[%s]", strings.Join(fields, ", ")))
+		}
+		if ba.Schema != "" {
+			args = append(args, fmt.Sprintf("schema=\"%s\"", ba.Schema))
+		}
+		text = fmt.Sprintf("%s(%s)", ba.Type, strings.Join(args, ", "))
+	case BrowserActionTypeHover:
+		text = fmt.Sprintf("%s(id=%s)", ba.Type, ba.ID)
+	case BrowserActionTypeDrag:
+		text = fmt.Sprintf("%s(from_id=%s, to_id=%s)", ba.Type, ba.FromID, ba.ToID)
+	case BrowserActionTypeClickAt:
+		text = fmt.Sprintf("%s(x=%g, y=%g)", ba.Type, ba.X, ba.Y)
 	case BrowserActionTypeTaskComplete:
 		text = fmt.Sprintf("%s(reason=\"%s\")", ba.Type, ba.Reason)
 	case BrowserActionTypeTaskNotPossible:
@@ -0,0 +1,44 @@
+package trajectory
+
+import "fmt"
+
+type DownloadObservation struct {
+	DontHandoff
+	Render
+	ItemIsNotMessage
+
+	URL      string `json:"url"`
+	FileName string `json:"file_name"`
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	MIMEType string `json:"mime_type"`
+}
+
+func NewDownloadObservation(url string, fileName string, path string, size int64, mimeType string) TrajectoryItem {
+	return &DownloadObservation{
+		URL:      url,
+		FileName: fileName,
+		Path:     path,
+		Size:     size,
+		MIMEType: mimeType,
+	}
+}
+
+func (d *DownloadObservation) GetText() string {
+	return fmt.Sprintf("observation: downloaded \"%s\" (%s, %s) from %s", d.FileName, formatSize(d.Size), d.MIMEType, d.URL)
+}
+
+func (d *DownloadObservation) GetAbbreviatedText() string {
+	return fmt.Sprintf("observation: downloaded \"%s\" (%s, %s)", d.FileName, formatSize(d.Size), d.MIMEType)
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
@@ -0,0 +1,103 @@
+package trajectory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The rows that an extract action returned, which are kept in full while its observation may be trimmed.
+type Extraction struct {
+	DontHandoff
+	DontRender
+	ItemIsNotMessage
+
+	// the number of browser actions that were taken before the rows were extracted
+	Step     int        `json:"step"`
+	Source   string     `json:"source"`
+	Location string     `json:"location"`
+	Fields   []string   `json:"fields"`
+	Rows     [][]string `json:"rows"`
+}
+
+func NewExtraction(step int, source string, location string, fields []string, rows [][]string) TrajectoryItem {
+	return &Extraction{
+		Step:     step,
+		Source:   source,
+		Location: location,
+		Fields:   fields,
+		Rows:     rows,
+	}
+}
+
+// Returns the name of the artifacts of the extraction without an extension.
+func (e *Extraction) BaseName() string {
+	return fmt.Sprintf("step-%03d", e.Step)
+}
+
+// Encodes the rows as a JSON array of objects whose keys are the fields, in the order of the fields.
+func (e *Extraction) JSON() ([]byte, error) {
+	return FormatRowsAsJSON(e.Fields, e.Rows)
+}
+
+// Encodes the rows as CSV with a header row of the fields.
+func (e *Extraction) CSV() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	if err := w.Write(e.Fields); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(e.Rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *Extraction) GetText() string {
+	return fmt.Sprintf("extraction: %s.json and %s.csv (%d rows with fields [%s] from %s of %s)", e.BaseName(), e.BaseName(), len(e.Rows), strings.Join(e.Fields, ", "), e.Source, e.Location)
+}
+
+func (e *Extraction) GetAbbreviatedText() string {
+	return e.GetText()
+}
+
+// Encodes rows as a JSON array of objects whose keys are the fields. Unlike maps, the keys keep the order of the
+// fields.
+func FormatRowsAsJSON(fields []string, rows [][]string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("[")
+	for i, row := range rows {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for j, field := range fields {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			value := ""
+			if j < len(row) {
+				value = row[j]
+			}
+			key, err := json.Marshal(field)
+			if err != nil {
+				return nil, err
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(key)
+			buf.WriteString(": ")
+			buf.Write(encoded)
+		}
+		buf.WriteString("}")
+	}
+	if len(rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]")
+	return buf.Bytes(), nil
+}
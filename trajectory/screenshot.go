@@ -0,0 +1,40 @@
+package trajectory
+
+import "fmt"
+
+type Screenshot struct {
+	DontHandoff
+	DontRender
+	ItemIsNotMessage
+
+	// the number of browser actions that were taken before the screenshot was captured
+	Step     int
+	Data     []byte
+	FullPage bool
+	Location string
+}
+
+func NewScreenshot(step int, data []byte, fullPage bool, location string) TrajectoryItem {
+	return &Screenshot{
+		Step:     step,
+		Data:     data,
+		FullPage: fullPage,
+		Location: location,
+	}
+}
+
+func (s *Screenshot) FileName() string {
+	return fmt.Sprintf("step-%03d.png", s.Step)
+}
+
+func (s *Screenshot) GetText() string {
+	kind := "viewport"
+	if s.FullPage {
+		kind = "full page"
+	}
+	return fmt.Sprintf("screenshot: %s (%s of %s)", s.FileName(), kind, s.Location)
+}
+
+func (s *Screenshot) GetAbbreviatedText() string {
+	return s.GetText()
+}
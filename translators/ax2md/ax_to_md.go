@@ -0,0 +1,122 @@
+package ax2md
+
+import (
+	"collaborativebrowser/translators"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/cdproto/cdp"
+)
+
+const DefaultMaxNameLength = 200
+
+// The input of the translator. The browser serializes it as JSON so that the translator fits the
+// translators.Translator interface.
+type Document struct {
+	// The nodes of the full accessibility tree, as returned by Accessibility.getFullAXTree. Nodes of child frames
+	// are attached to the node of their owner element.
+	Nodes []*accessibility.Node `json:"nodes"`
+	// The virtual ids of the DOM nodes that have them.
+	VirtualIDs map[cdp.BackendNodeID]string `json:"virtualIds"`
+}
+
+type AX2MDTranslator struct {
+	maxNameLength int
+}
+
+type Options struct {
+	maxNameLength int
+}
+
+func NewAX2MDTranslator(options *Options) translators.Translator {
+	maxNameLength := DefaultMaxNameLength
+	if options != nil {
+		if options.maxNameLength > 0 {
+			maxNameLength = options.maxNameLength
+		}
+	}
+	return &AX2MDTranslator{
+		maxNameLength: maxNameLength,
+	}
+}
+
+// Translates a JSON encoded Document into a nested list with one line per node that has a role or a name.
+func (t *AX2MDTranslator) Translate(text string) (string, error) {
+	var doc Document
+	if err := json.Unmarshal([]byte(text), &doc); err != nil {
+		return "", fmt.Errorf("error parsing accessibility tree: %w", err)
+	}
+	nodes := make(map[accessibility.NodeID]*accessibility.Node)
+	for _, n := range doc.Nodes {
+		nodes[n.NodeID] = n
+	}
+	lines := []string{}
+	for _, n := range doc.Nodes {
+		// roots are nodes whose parent is not part of the tree
+		if _, ok := nodes[n.ParentID]; !ok || n.ParentID == "" {
+			lines = t.visit(n, nodes, doc.VirtualIDs, "", 0, lines)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (t *AX2MDTranslator) visit(n *accessibility.Node, nodes map[accessibility.NodeID]*accessibility.Node, virtualIDs map[cdp.BackendNodeID]string, parentName string, depth int, lines []string) []string {
+	role := valueString(n.Role)
+	name := valueString(n.Name)
+	virtualID := virtualIDs[n.BackendDOMNodeID]
+	childDepth := depth
+	switch {
+	case n.Ignored, skippedRoles[role]:
+		// the node is not rendered but its children are
+	case role == "StaticText":
+		// text that repeats the name of its parent, such as the label of a link, is already rendered
+		if name != "" && !strings.Contains(parentName, name) {
+			lines = append(lines, indent(depth)+t.truncate(name))
+		}
+		return lines
+	case transparentRoles[role] && name == "" && virtualID == "":
+		// containers without a name only add nesting
+	default:
+		lines = append(lines, indent(depth)+t.renderNode(n, role, name, virtualID))
+		childDepth = depth + 1
+		parentName = name
+	}
+	for _, childID := range n.ChildIDs {
+		if child, ok := nodes[childID]; ok {
+			lines = t.visit(child, nodes, virtualIDs, parentName, childDepth, lines)
+		}
+	}
+	return lines
+}
+
+func (t *AX2MDTranslator) renderNode(n *accessibility.Node, role string, name string, virtualID string) string {
+	var sb strings.Builder
+	sb.WriteString("- ")
+	sb.WriteString(role)
+	if name != "" {
+		sb.WriteString(fmt.Sprintf(" \"%s\"", t.truncate(name)))
+	}
+	if value := valueString(n.Value); value != "" {
+		sb.WriteString(fmt.Sprintf(" value=\"%s\"", t.truncate(value)))
+	}
+	details := []string{}
+	if virtualID != "" {
+		details = append(details, virtualID)
+	}
+	details = append(details, renderStates(n.Properties)...)
+	if len(details) > 0 {
+		sb.WriteString(" (" + strings.Join(details, ", ") + ")")
+	}
+	return sb.String()
+}
+
+func (t *AX2MDTranslator) truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	// names are cut by characters rather than bytes so that multi-byte characters are not split
+	if runes := []rune(s); len(runes) > t.maxNameLength {
+		return string(runes[:t.maxNameLength]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+package ax2md
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxNameLength int
+		input         string
+		want          string
+	}{
+		{"short", 10, "Submit", "Submit"},
+		{"exact length", 6, "Submit", "Submit"},
+		{"long", 6, "Submit the form", "Submit..."},
+		{"collapses whitespace", 20, "  Submit \n\t the   form ", "Submit the form"},
+		{"collapses whitespace before cutting", 8, "Submit\n\n\nthe form", "Submit t..."},
+		{"multi-byte characters", 3, "日本語のページ", "日本語..."},
+		{"emoji", 2, "👍👍👍", "👍👍..."},
+		{"mixed", 4, "café au lait", "café..."},
+		{"empty", 5, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			translator := NewAX2MDTranslator(&Options{maxNameLength: test.maxNameLength}).(*AX2MDTranslator)
+			if got := translator.truncate(test.input); got != test.want {
+				t.Errorf("truncate(%q) = %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+}
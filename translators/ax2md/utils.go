@@ -0,0 +1,80 @@
+package ax2md
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/accessibility"
+)
+
+// Roles that are never rendered. Inline text boxes repeat the text of their static text parent.
+var skippedRoles = map[string]bool{
+	"InlineTextBox": true,
+	"LineBreak":     true,
+	"none":          true,
+}
+
+// Roles that are only rendered when they have a name or a virtual id.
+var transparentRoles = map[string]bool{
+	"generic":          true,
+	"GenericContainer": true,
+	"group":            true,
+	"paragraph":        true,
+	"RootWebArea":      true,
+	"Section":          true,
+}
+
+// States that are rendered with their value.
+var valuedStates = map[accessibility.PropertyName]bool{
+	accessibility.PropertyNameChecked:  true,
+	accessibility.PropertyNameExpanded: true,
+	accessibility.PropertyNamePressed:  true,
+	accessibility.PropertyNameSelected: true,
+	accessibility.PropertyNameLevel:    true,
+}
+
+// States that are only rendered by name when they are true.
+var flagStates = map[accessibility.PropertyName]bool{
+	accessibility.PropertyNameDisabled: true,
+	accessibility.PropertyNameRequired: true,
+	accessibility.PropertyNameReadonly: true,
+	accessibility.PropertyNameInvalid:  true,
+	accessibility.PropertyNameFocused:  true,
+}
+
+func renderStates(properties []*accessibility.Property) []string {
+	states := []string{}
+	for _, property := range properties {
+		value := valueString(property.Value)
+		if valuedStates[property.Name] && value != "" {
+			states = append(states, fmt.Sprintf("%s=%s", property.Name, value))
+		} else if flagStates[property.Name] && value != "" && value != "false" {
+			states = append(states, string(property.Name))
+		}
+	}
+	return states
+}
+
+// Returns the value of an accessibility value as a string, or an empty string if it has none.
+func valueString(v *accessibility.Value) string {
+	if v == nil || len(v.Value) == 0 {
+		return ""
+	}
+	var value any
+	if err := json.Unmarshal(v.Value, &value); err != nil {
+		return strings.Trim(string(v.Value), `"`)
+	}
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func indent(depth int) string {
+	return strings.Repeat("  ", depth)
+}
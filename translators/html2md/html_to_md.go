@@ -27,6 +27,25 @@ const (
 	SelectableTypeLink   SelectableType = "link"
 	SelectableTypeInput  SelectableType = "input"
 	SelectableTypeTextA  SelectableType = "textarea"
+	SelectableTypeSelect SelectableType = "select"
+	SelectableTypeCheck  SelectableType = "checkbox"
+	SelectableTypeRadio  SelectableType = "radio"
+	SelectableTypeFile   SelectableType = "file"
+)
+
+const DefaultMaxSelectOptionsDisplaySize = 20
+
+// Comment markers that are inserted by the browser when rendering in viewport mode.
+const (
+	ViewportMarkerAbove = "collaborativebrowser-viewport-above"
+	ViewportMarkerStart = "collaborativebrowser-viewport-start"
+	ViewportMarkerEnd   = "collaborativebrowser-viewport-end"
+)
+
+// Elements that are inserted by the browser in place of iframe documents and open shadow roots.
+const (
+	FrameElement      = "cb-frame"
+	ShadowRootElement = "cb-shadow-root"
 )
 
 func NewHTML2MDTranslator(options *Options) translators.Translator {
@@ -71,14 +90,44 @@ func (t *HTML2MDTranslator) Visit(n *html.Node) string {
 			} else {
 				return renderSelectable(SelectableTypeButton, virtualID, label, "")
 			}
+		case "select":
+			if _, ok := attrMap["data-vid"]; !ok {
+				return ""
+			}
+			return renderSelectable(SelectableTypeSelect, virtualID, getLabelForFormControl(n, attrMap), renderSelectOptions(n))
 		case "input", "textarea":
-			if !isInputable(n, attrMap) {
+			if n.Data == "input" && attrMap["type"] == "file" {
+				if _, ok := attrMap["data-vid"]; !ok {
+					return ""
+				}
+				return renderSelectable(SelectableTypeFile, virtualID, getLabelForFormControl(n, attrMap), renderAttachedFiles(attrMap))
+			} else if typ := attrMap["type"]; n.Data == "input" && (typ == "checkbox" || typ == "radio") {
+				if _, ok := attrMap["data-vid"]; !ok {
+					return ""
+				}
+				return renderSelectable(SelectableType(typ), virtualID, getLabelForFormControl(n, attrMap), fmt.Sprintf("checked=%t", isChecked(attrMap)))
+			} else if !isInputable(n, attrMap) {
 				return strings.Join(content, "\n")
 			} else if label, isInputable := getLabelForInputable(n, attrMap); !isInputable {
 				return strings.Join(content, "\n")
 			} else {
 				return renderSelectable(SelectableType(n.Data), virtualID, label, "")
 			}
+		case "label":
+			// label text is rendered as part of the controls that it describes
+			controls := []string{}
+			i := 0
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if hasVirtualID(c) {
+					controls = append(controls, content[i])
+				}
+				i++
+			}
+			return strings.Join(controls, " ")
+		case FrameElement:
+			return renderFrame(attrMap, strings.Join(content, "\n"))
+		case ShadowRootElement:
+			return renderShadowRoot(n, strings.Join(content, "\n"))
 		case "b", "strong":
 			return "**" + strings.Join(content, "") + "**"
 		case "i", "em":
@@ -147,13 +196,24 @@ func (t *HTML2MDTranslator) Visit(n *html.Node) string {
 			return strings.Join(content, "\n")
 		case "p", "span", "g", "figure", "desc", "footer", "html", "legend", "fieldset", "center", "picture":
 			return strings.Join(content, " ")
-		case "head", "script", "style", "iframe", "svg", "meso-native", "meso-display-ad", "grammarly-desktop-integration", "path", "noscript", "link", "meta", "label", "circle", "rect", "image", "polygon", "source", "use", "canvas":
+		case "head", "script", "style", "iframe", "svg", "meso-native", "meso-display-ad", "grammarly-desktop-integration", "path", "noscript", "link", "meta", "circle", "rect", "image", "polygon", "source", "use", "canvas", "option", "optgroup":
 			return ""
 		default:
 			log.Printf("Found unknown element: %v\n", n.Data)
 			return strings.Join(content, "\n")
 		}
-	case html.CommentNode, html.DoctypeNode:
+	case html.CommentNode:
+		switch n.Data {
+		case ViewportMarkerAbove:
+			return "\n\n----- ABOVE VIEWPORT -----\n\n"
+		case ViewportMarkerStart:
+			return "\n\n----- IN VIEWPORT -----\n\n"
+		case ViewportMarkerEnd:
+			return "\n\n----- BELOW VIEWPORT -----\n\n"
+		default:
+			return ""
+		}
+	case html.DoctypeNode:
 		return ""
 	case html.DocumentNode:
 		content := t.visitChildren(n)
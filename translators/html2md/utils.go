@@ -34,10 +34,14 @@ func shouldVisitNode(n *html.Node) bool {
 		return true
 	}
 	if n.Data == "input" || n.Data == "textarea" {
-		for _, attr := range n.Attr {
-			if attr.Key == "type" && attr.Val == "hidden" {
-				return false
-			}
+		attrMap := buildAttrMapFromNode(n)
+		if attrMap["type"] == "hidden" {
+			return false
+		}
+		// file inputs are usually hidden behind a styled label or button, so they are rendered whenever the
+		// browser gave them a virtual id
+		if _, ok := attrMap["data-vid"]; ok && attrMap["type"] == "file" {
+			return true
 		}
 	}
 	for _, attr := range n.Attr {
@@ -236,3 +240,160 @@ func stripQueryParamsFromPossibleFullURL(link string) string {
 	parsedURL.RawQuery = ""
 	return parsedURL.String()
 }
+
+// Returns the label for a select, checkbox, or radio button from its aria label, its associated label
+// element, or its name.
+func getLabelForFormControl(n *html.Node, attrMap map[string]string) string {
+	if ariaLabel, ok := attrMap["aria-label"]; ok && strings.TrimSpace(ariaLabel) != "" {
+		return strings.TrimSpace(ariaLabel)
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "label" {
+			if text := getTextContent(p); text != "" {
+				return text
+			}
+			break
+		}
+	}
+	if id, ok := attrMap["id"]; ok && id != "" {
+		if label := findNode(rootOf(n), func(c *html.Node) bool {
+			return c.Type == html.ElementNode && c.Data == "label" && buildAttrMapFromNode(c)["for"] == id
+		}); label != nil {
+			if text := getTextContent(label); text != "" {
+				return text
+			}
+		}
+	}
+	if name, ok := attrMap["name"]; ok && name != "" {
+		if value, ok := attrMap["value"]; ok && value != "" && n.Data == "input" && attrMap["type"] == "radio" {
+			return fmt.Sprintf("%s=%s", name, value)
+		}
+		return name
+	}
+	return ""
+}
+
+// Renders the names of the files that are attached to a file input, which the browser stores in data-files.
+func renderAttachedFiles(attrMap map[string]string) string {
+	if files := strings.TrimSpace(attrMap["data-files"]); files != "" {
+		return fmt.Sprintf("files=\"%s\"", files)
+	}
+	return "no files attached"
+}
+
+func isChecked(attrMap map[string]string) bool {
+	if checked, ok := attrMap["data-checked"]; ok {
+		return checked == "true"
+	}
+	_, ok := attrMap["checked"]
+	return ok
+}
+
+func isSelected(attrMap map[string]string) bool {
+	if selected, ok := attrMap["data-selected"]; ok {
+		return selected == "true"
+	}
+	_, ok := attrMap["selected"]
+	return ok
+}
+
+func renderSelectOptions(n *html.Node) string {
+	options := []string{}
+	selected := ""
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		for c := c.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			} else if c.Data == "optgroup" {
+				visit(c)
+			} else if c.Data == "option" {
+				attrMap := buildAttrMapFromNode(c)
+				label := getTextContent(c)
+				if label == "" {
+					label = attrMap["value"]
+				}
+				if isSelected(attrMap) {
+					selected = label
+				}
+				options = append(options, fmt.Sprintf("\"%s\"", label))
+			}
+		}
+	}
+	visit(n)
+	if len(options) > DefaultMaxSelectOptionsDisplaySize {
+		numHidden := len(options) - DefaultMaxSelectOptionsDisplaySize
+		options = append(options[:DefaultMaxSelectOptionsDisplaySize], fmt.Sprintf("...%d more", numHidden))
+	}
+	return fmt.Sprintf("options=[%s], selected=\"%s\"", strings.Join(options, ", "), selected)
+}
+
+func getTextContent(n *html.Node) string {
+	var sb strings.Builder
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+			sb.WriteString(" ")
+		}
+		for c := c.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func rootOf(n *html.Node) *html.Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+func findNode(n *html.Node, f func(*html.Node) bool) *html.Node {
+	if f(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findNode(c, f); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func hasVirtualID(n *html.Node) bool {
+	return findNode(n, func(c *html.Node) bool {
+		return c.Type == html.ElementNode && buildAttrMapFromNode(c)["data-vid"] != ""
+	}) != nil
+}
+
+func renderFrame(attrMap map[string]string, content string) string {
+	var description string
+	if title, ok := attrMap["title"]; ok && strings.TrimSpace(title) != "" {
+		description = fmt.Sprintf(" title=%s", strings.TrimSpace(title))
+	} else if name, ok := attrMap["name"]; ok && name != "" {
+		description = fmt.Sprintf(" name=%s", name)
+	}
+	if src, ok := attrMap["src"]; ok && src != "" {
+		description += fmt.Sprintf(" src=%s", stripQueryParamsFromPossibleFullURL(src))
+	}
+	if attrMap["data-cross-origin"] == "true" {
+		return fmt.Sprintf("\n\n----- CROSS-ORIGIN FRAME%s (content not available) -----\n\n", description)
+	} else if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n\n----- START FRAME%s -----\n\n%s\n\n----- END FRAME -----\n\n", description, content)
+}
+
+func renderShadowRoot(n *html.Node, content string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	var host string
+	if n.Parent != nil && n.Parent.Type == html.ElementNode {
+		host = " host=" + n.Parent.Data
+	}
+	return fmt.Sprintf("\n\n----- START SHADOW ROOT%s -----\n\n%s\n\n----- END SHADOW ROOT -----\n\n", host, content)
+}